@@ -0,0 +1,121 @@
+package admin
+
+import (
+	"html/template"
+	"time"
+)
+
+var pages = template.Must(template.New("admin").Funcs(template.FuncMap{
+	"Time": func(t time.Time) string {
+		if t.IsZero() {
+			return "-"
+		}
+		return t.UTC().Format("2006-01-02 15:04:05")
+	},
+	"Seconds": func(s float64) string {
+		return (time.Duration(s) * time.Second).String()
+	},
+}).Parse(layout))
+
+// layout holds the pages of the admin ui, they are self contained so that no asset has to be served
+const layout = `
+{{define "head"}}<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>tezos-bot admin</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+h1 { font-size: 1.4em; }
+h2 { font-size: 1.1em; margin-top: 2em; border-bottom: 1px solid #ccc; }
+table { border-collapse: collapse; }
+th, td { text-align: left; padding: .25em .75em .25em 0; vertical-align: top; }
+th { color: #666; font-weight: normal; }
+.ok { color: #1a7f37; }
+.bad { color: #cf222e; }
+.muted { color: #888; }
+form.inline { display: inline; }
+textarea, input[type=text], input[type=password] { width: 32em; font-family: monospace; }
+pre { background: #f6f8fa; padding: .75em; white-space: pre-wrap; max-width: 60em; }
+</style>
+</head>
+<body>
+{{end}}
+
+{{define "login"}}{{template "head"}}
+<h1>tezos-bot admin</h1>
+<form method="post" action="/login">
+<p><input type="password" name="token" placeholder="Admin token" autofocus> <button type="submit">Sign in</button></p>
+{{with .Error}}<p class="bad">{{.}}</p>{{end}}
+</form>
+</body>
+</html>
+{{end}}
+
+{{define "index"}}{{template "head"}}
+<h1>tezos-bot admin <small class="muted">{{Time .Now}} UTC, <a href="/">refresh</a></small></h1>
+
+<h2>Listener</h2>
+{{with .Report}}
+<table>
+<tr><th>Mode</th><td>{{.Mode}}</td></tr>
+<tr><th>Block stream</th><td class="{{if eq .StreamState "streaming"}}ok{{else}}bad{{end}}">{{or .StreamState "-"}}</td></tr>
+<tr><th>Last head</th><td>{{if .LastHeadTime}}{{.LastHeadLevel}} <span class="muted">{{.LastHeadHash}}</span>, {{Seconds .SecondsSinceLastHead}} ago{{else}}none yet{{end}}</td></tr>
+<tr><th>Lag</th><td>{{$.Lag}} blocks behind the rpc nodes</td></tr>
+<tr><th>Stream reconnects</th><td>{{.StreamReconnects}}</td></tr>
+{{with .NodeError}}<tr><th>Node error</th><td class="bad">{{.}}</td></tr>{{end}}
+{{with .Backfill}}<tr><th>Backfill</th><td>level {{.CurrentLevel}} of {{.StartLevel}} to {{.EndLevel}} ({{printf "%.1f" .Percent}}%)</td></tr>{{end}}
+</table>
+{{end}}
+{{if .Nodes}}
+<table>
+<tr><th>RPC node</th><th>Level</th><th>State</th></tr>
+{{range .Nodes}}<tr><td>{{.URL}}</td><td>{{.Level}}</td><td>{{if not .Reachable}}<span class="bad">unreachable {{.Error}}</span>{{else if .Active}}<span class="ok">active</span>{{else}}standby{{end}}</td></tr>
+{{end}}
+</table>
+{{end}}
+
+<h2>Publishers</h2>
+{{range .Publishers}}
+<h3>{{.Name}} {{if .Paused}}<span class="bad">paused</span>{{else}}<span class="ok">running</span>{{end}}
+<form class="inline" method="post" action="/publishers/{{.Name}}/{{if .Paused}}resume{{else}}pause{{end}}"><button type="submit">{{if .Paused}}Resume{{else}}Pause{{end}}</button></form></h3>
+<p>{{.Published}} published, {{.Failed}} rejected, {{.Skipped}} skipped while paused</p>
+{{if .LastErrors}}
+<table>
+<tr><th>Last errors</th><th>Event</th><th>Error</th></tr>
+{{range .LastErrors}}<tr><td>{{Time .At}}</td><td>{{.Kind}}</td><td class="bad">{{.Error}}</td></tr>
+{{end}}
+</table>
+{{else}}<p class="muted">No error since the bot started</p>{{end}}
+{{else}}<p class="muted">No publisher</p>
+{{end}}
+
+<h2>Recent events</h2>
+{{if not .EventStore}}<p class="muted">The event store is disabled, set event_store to record the events.</p>
+{{else}}
+{{with .EventsErr}}<p class="bad">{{.}}</p>{{end}}
+<table>
+<tr><th>#</th><th>Recorded</th><th>Kind</th><th>Level</th><th>Publications</th></tr>
+{{range .Events}}<tr><td>{{.ID}}</td><td>{{Time .RecordedAt}}</td><td>{{.Kind}}</td><td>{{if .Level}}{{.Level}}{{end}}</td>
+<td>{{range .Publications}}{{.Publisher}}: {{if eq .Status "published"}}<span class="ok">accepted</span>{{else if eq .Status "failed"}}<span class="bad">rejected</span> {{.Error}}{{else}}<span class="muted">{{.Status}}</span>{{end}}<br>{{end}}</td></tr>
+{{else}}<tr><td colspan="5" class="muted">No event recorded yet</td></tr>
+{{end}}
+</table>
+{{end}}
+
+<h2>Message preview</h2>
+<form method="post" action="/preview">
+<p>Announcement template <input type="text" name="template" value="{{.Form.Template}}" placeholder="period_soon"></p>
+<p>Variables, one name=value per line<br><textarea name="vars" rows="3">{{.Form.Vars}}</textarea></p>
+<p>or free text<br><textarea name="text" rows="3">{{.Form.Text}}</textarea></p>
+<p>or an event as recorded in the event store, {"kind": ..., "payload": ...}<br><textarea name="event" rows="5">{{.Form.Event}}</textarea></p>
+<p><button type="submit">Preview</button></p>
+</form>
+{{with .Preview}}
+{{if .Error}}<p class="bad">{{.Error}}</p>{{end}}
+{{if .Text}}<pre>{{.Text}}</pre>{{end}}
+{{end}}
+</body>
+</html>
+{{end}}
+`
@@ -0,0 +1,378 @@
+package admin
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ecadlabs/tezos-bot/health"
+	"github.com/ecadlabs/tezos-bot/models"
+	"github.com/ecadlabs/tezos-bot/service"
+	"github.com/ecadlabs/tezos-bot/store"
+)
+
+const (
+	// recentEvents is the number of events listed on the dashboard
+	recentEvents = 50
+	tokenCookie  = "tezos_bot_admin"
+)
+
+// AdminConfig interface with method necessary to obtain admin server configurable parameter
+type AdminConfig interface {
+	GetAdminAddr() string
+	GetAdminToken() string
+	GetAdminUser() string
+	GetAdminPassword() string
+}
+
+// EventReader interface with method necessary to read the last recorded events
+type EventReader interface {
+	Recent(limit int) ([]*store.Event, error)
+}
+
+// Previewer interface with method necessary to render the message of an event without publishing it
+type Previewer interface {
+	Preview(kind string, payload json.RawMessage) (string, error)
+}
+
+// Server serves the admin web ui: the state of the listener and the publishers, the recent events and a message preview
+type Server struct {
+	config    AdminConfig
+	status    *health.Status
+	events    EventReader
+	controls  []*service.Control
+	previewer Previewer
+	server    *http.Server
+}
+
+// NewServer create a new admin Server, events may be nil when the event store is disabled
+func NewServer(config AdminConfig, status *health.Status, events EventReader, controls []*service.Control, previewer Previewer) *Server {
+	s := &Server{
+		config:    config,
+		status:    status,
+		events:    events,
+		controls:  controls,
+		previewer: previewer,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", s.handleLogin)
+	mux.Handle("/", s.authenticate(http.HandlerFunc(s.handleIndex)))
+	mux.Handle("/publishers/", s.authenticate(http.HandlerFunc(s.handlePublisher)))
+	mux.Handle("/preview", s.authenticate(http.HandlerFunc(s.handlePreview)))
+
+	s.server = &http.Server{
+		Addr:    config.GetAdminAddr(),
+		Handler: mux,
+	}
+
+	return s
+}
+
+// Start serves the admin web ui in the background
+func (s *Server) Start() {
+	go func() {
+		log.Printf("Admin server listening on %s\n", s.server.Addr)
+		if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Printf("Admin server stopped because of error: %s\n", err.Error())
+		}
+	}()
+}
+
+// Stop stop the admin server
+func (s *Server) Stop() error {
+	return s.server.Close()
+}
+
+// authorized returns true if the request carries the basic auth credentials, or the token as a bearer token or cookie
+func (s *Server) authorized(r *http.Request) bool {
+	if user := s.config.GetAdminUser(); user != "" {
+		u, p, ok := r.BasicAuth()
+		if ok && equal(u, user) && equal(p, s.config.GetAdminPassword()) {
+			return true
+		}
+	}
+
+	if token := s.config.GetAdminToken(); token != "" {
+		if h := r.Header.Get("Authorization"); strings.HasPrefix(h, "Bearer ") && equal(strings.TrimPrefix(h, "Bearer "), token) {
+			return true
+		}
+		if c, err := r.Cookie(tokenCookie); err == nil && equal(c.Value, token) {
+			return true
+		}
+	}
+	return false
+}
+
+// authenticate rejects the requests that are not authorized, and the forms posted from another site
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.authorized(r) {
+			if s.config.GetAdminUser() != "" {
+				w.Header().Set("WWW-Authenticate", `Basic realm="tezos-bot admin"`)
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			if r.Method == http.MethodGet {
+				http.Redirect(w, r, "/login", http.StatusSeeOther)
+				return
+			}
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		if r.Method == http.MethodPost && !sameOrigin(r) {
+			http.Error(w, "cross origin request rejected", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	token := s.config.GetAdminToken()
+	if token == "" {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	data := loginData{}
+	if r.Method == http.MethodPost {
+		if equal(r.PostFormValue("token"), token) {
+			http.SetCookie(w, &http.Cookie{
+				Name:     tokenCookie,
+				Value:    token,
+				Path:     "/",
+				HttpOnly: true,
+				SameSite: http.SameSiteStrictMode,
+			})
+			http.Redirect(w, r, "/", http.StatusSeeOther)
+			return
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+		data.Error = "Invalid token"
+	}
+	render(w, "login", data)
+}
+
+func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	render(w, "index", s.pageData())
+}
+
+// handlePublisher pauses or resumes a publisher, POST /publishers/{name}/pause|resume
+func (s *Server) handlePublisher(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/publishers/"), "/")
+	if len(parts) != 2 || (parts[1] != "pause" && parts[1] != "resume") {
+		http.NotFound(w, r)
+		return
+	}
+
+	for _, c := range s.controls {
+		if c.Name() == parts[0] {
+			c.SetPaused(parts[1] == "pause")
+			log.Printf("Admin: %s publisher %sd\n", c.Name(), parts[1])
+			http.Redirect(w, r, "/", http.StatusSeeOther)
+			return
+		}
+	}
+	http.NotFound(w, r)
+}
+
+// handlePreview renders the message of an announcement or of an event posted as JSON
+func (s *Server) handlePreview(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	form := previewForm{
+		Template: strings.TrimSpace(r.PostFormValue("template")),
+		Vars:     r.PostFormValue("vars"),
+		Text:     r.PostFormValue("text"),
+		Event:    strings.TrimSpace(r.PostFormValue("event")),
+	}
+	result := &previewResult{}
+
+	kind, payload, err := form.event()
+	if err == nil {
+		result.Kind = kind
+		result.Text, err = s.previewer.Preview(kind, payload)
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	data := s.pageData()
+	data.Form = form
+	data.Preview = result
+	render(w, "index", data)
+}
+
+type loginData struct {
+	Error string
+}
+
+type pageData struct {
+	Report health.Report
+	// Lag is the number of blocks the last head is behind the most advanced rpc node
+	Lag        int
+	Nodes      []nodeRow
+	Publishers []service.ControlReport
+	EventStore bool
+	Events     []eventRow
+	EventsErr  string
+	Form       previewForm
+	Preview    *previewResult
+	Now        time.Time
+}
+
+type nodeRow struct {
+	URL string
+	health.NodeReport
+}
+
+type eventRow struct {
+	*store.Event
+	Publications []publicationRow
+}
+
+type publicationRow struct {
+	Publisher string
+	store.Publication
+}
+
+type previewResult struct {
+	Kind  string
+	Text  string
+	Error string
+}
+
+// previewForm is either an announcement, rendered from a template or free text, or an event as recorded in the event store
+type previewForm struct {
+	Template string
+	// Vars holds one name=value template variable per line
+	Vars  string
+	Text  string
+	Event string
+}
+
+// event returns the kind and payload of the event to preview
+func (f previewForm) event() (string, json.RawMessage, error) {
+	if f.Event != "" {
+		var e struct {
+			Kind    string          `json:"kind"`
+			Payload json.RawMessage `json:"payload"`
+		}
+		if err := json.Unmarshal([]byte(f.Event), &e); err != nil {
+			return "", nil, fmt.Errorf("invalid event: %s", err.Error())
+		}
+		if e.Kind == "" || len(e.Payload) == 0 {
+			return "", nil, fmt.Errorf("the event must have a kind and a payload")
+		}
+		return e.Kind, e.Payload, nil
+	}
+
+	if (f.Template == "") == (strings.TrimSpace(f.Text) == "") {
+		return "", nil, fmt.Errorf("exactly one of a template, a text or an event is required")
+	}
+
+	vars := make(map[string]string)
+	for _, line := range strings.Split(f.Vars, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		i := strings.Index(line, "=")
+		if i <= 0 {
+			return "", nil, fmt.Errorf("%q is not a name=value pair", line)
+		}
+		vars[strings.TrimSpace(line[:i])] = strings.TrimSpace(line[i+1:])
+	}
+
+	payload, err := json.Marshal(&models.Announcement{
+		BlockRef: models.BlockRef{Timestamp: time.Now().UTC()},
+		Template: f.Template,
+		Vars:     vars,
+		Text:     f.Text,
+	})
+	return models.EventAnnouncement, payload, err
+}
+
+func (s *Server) pageData() *pageData {
+	data := &pageData{
+		Report:     s.status.Report(),
+		EventStore: s.events != nil,
+		Now:        time.Now().UTC(),
+	}
+
+	for u, n := range data.Report.Nodes {
+		data.Nodes = append(data.Nodes, nodeRow{URL: u, NodeReport: n})
+		if lag := n.Level - data.Report.LastHeadLevel; n.Reachable && lag > data.Lag {
+			data.Lag = lag
+		}
+	}
+	sort.Slice(data.Nodes, func(i, j int) bool { return data.Nodes[i].URL < data.Nodes[j].URL })
+
+	for _, c := range s.controls {
+		data.Publishers = append(data.Publishers, c.Report())
+	}
+
+	if s.events != nil {
+		events, err := s.events.Recent(recentEvents)
+		if err != nil {
+			data.EventsErr = err.Error()
+		}
+		for _, e := range events {
+			row := eventRow{Event: e}
+			for _, c := range s.controls {
+				p, ok := e.Publications[c.Name()]
+				if !ok {
+					p = store.Publication{Status: "not published"}
+				}
+				row.Publications = append(row.Publications, publicationRow{Publisher: c.Name(), Publication: p})
+			}
+			data.Events = append(data.Events, row)
+		}
+	}
+	return data
+}
+
+func render(w http.ResponseWriter, name string, data interface{}) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("X-Frame-Options", "DENY")
+	if err := pages.ExecuteTemplate(w, name, data); err != nil {
+		log.Printf("Unable to render admin page: %s\n", err.Error())
+	}
+}
+
+// sameOrigin returns false if the request was sent by a page of another site
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		origin = r.Header.Get("Referer")
+	}
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == r.Host
+}
+
+func equal(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ecadlabs/tezos-bot/health"
+	"github.com/ecadlabs/tezos-bot/models"
+	"github.com/ecadlabs/tezos-bot/service"
+	"github.com/ecadlabs/tezos-bot/store"
+)
+
+// varsFlag collects the name=value pairs of a repeated flag
+type varsFlag map[string]string
+
+func (v varsFlag) String() string {
+	pairs := []string{}
+	for name, value := range v {
+		pairs = append(pairs, name+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (v varsFlag) Set(s string) error {
+	i := strings.Index(s, "=")
+	if i <= 0 {
+		return fmt.Errorf("%q is not a name=value pair", s)
+	}
+	v[s[:i]] = s[i+1:]
+	return nil
+}
+
+func runAnnounce(args []string) int {
+	fs := newFlagSet("announce", "[flags]", "Post a message on demand through the publishers, rendered from an announcement template\n(templates/announce_<name>.tmpl) or given as free text. The message is shown for each publisher\nfirst and only published once confirmed.")
+	configFile := configFlag(fs)
+	name := fs.String("template", "", "Announcement template, e.g. period_soon for templates/announce_period_soon.tmpl.")
+	vars := varsFlag{}
+	fs.Var(vars, "var", "Template variable as name=value, can be repeated.")
+	text := fs.String("text", "", "Free text to post instead of a template.")
+	dryRun := fs.Bool("dry-run", false, "Only show the rendered messages.")
+	yes := fs.Bool("y", false, "Publish without asking for confirmation.")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if (*name == "") == (*text == "") {
+		fmt.Fprintln(os.Stderr, "announce: exactly one of -template or -text is required")
+		return 2
+	}
+
+	c, err := loadConfig(*configFile)
+	if err != nil {
+		log.Printf(err.Error())
+		return 1
+	}
+
+	announcement := &models.Announcement{
+		BlockRef: models.BlockRef{Timestamp: time.Now().UTC()},
+		Template: *name,
+		Vars:     vars,
+		Text:     *text,
+	}
+
+	payload, err := json.Marshal(announcement)
+	if err != nil {
+		log.Printf(err.Error())
+		return 1
+	}
+	preview := &previewSink{publishers: previewPublishers(c)}
+	fmt.Println("== announcement")
+	preview.render(models.EventAnnouncement, payload)
+	if preview.failed {
+		return 1
+	}
+	if *dryRun {
+		return 0
+	}
+
+	p, publisherName, err := newPublisher(c, health.NewStatus())
+	if err != nil {
+		log.Printf(err.Error())
+		return 1
+	}
+
+	if !*yes && !confirm(fmt.Sprintf("Publish with the %s publisher? [y/N] ", publisherName)) {
+		fmt.Println("Not published")
+		return 1
+	}
+
+	var events service.EventStore
+	if path := c.GetEventStore(); path != "" {
+		eventStore, err := store.Open(path)
+		if err != nil {
+			// The store is locked while the bot runs
+			log.Printf("The announcement will not be recorded, unable to open the event store: %s\n", err.Error())
+		} else {
+			defer eventStore.Close()
+			events = eventStore
+		}
+	}
+
+	if err := service.New(nil, p, publisherName, events).Announce(announcement); err != nil {
+		return 1
+	}
+
+	log.Println("Announcement published")
+	return 0
+}
+
+// confirm asks a yes or no question on the terminal, anything but yes is a no
+func confirm(prompt string) bool {
+	fmt.Print(prompt)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
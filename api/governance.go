@@ -0,0 +1,305 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ecadlabs/tezos-bot/models"
+	"github.com/ecadlabs/tezos-bot/store"
+)
+
+type periodResponse struct {
+	Index        int       `json:"index"`
+	Kind         string    `json:"kind"`
+	StartLevel   int       `json:"start_level"`
+	EndLevel     int       `json:"end_level"`
+	EstimatedEnd time.Time `json:"estimated_end"`
+	ProposalHash string    `json:"proposal_hash,omitempty"`
+	Outcome      *outcome  `json:"outcome,omitempty"`
+}
+
+type outcome struct {
+	ToIndex      int             `json:"to_index"`
+	ToKind       string          `json:"to_kind"`
+	ProposalHash string          `json:"proposal_hash,omitempty"`
+	Result       *resultResponse `json:"result,omitempty"`
+}
+
+type resultResponse struct {
+	Yay                  int64   `json:"yay"`
+	Nay                  int64   `json:"nay"`
+	Pass                 int64   `json:"pass"`
+	TotalVotingPower     int64   `json:"total_voting_power"`
+	Unit                 string  `json:"unit"`
+	PercentParticipation float64 `json:"percent_participation"`
+	Quorum               float64 `json:"quorum"`
+	Supermajority        float64 `json:"supermajority"`
+	PercentYay           float64 `json:"percent_yay"`
+	Passed               bool    `json:"passed"`
+	FailureReason        string  `json:"failure_reason,omitempty"`
+}
+
+type talliesResponse struct {
+	Period               int       `json:"period"`
+	Phase                string    `json:"phase"`
+	ProposalHash         string    `json:"proposal_hash"`
+	Yay                  int64     `json:"yay"`
+	Nay                  int64     `json:"nay"`
+	Pass                 int64     `json:"pass"`
+	TotalVotingPower     int64     `json:"total_voting_power"`
+	Unit                 string    `json:"unit"`
+	PercentParticipation float64   `json:"percent_participation"`
+	PercentYay           float64   `json:"percent_yay"`
+	Quorum               float64   `json:"quorum"`
+	ProjectedQuorum      float64   `json:"projected_quorum"`
+	QuorumReached        bool      `json:"quorum_reached"`
+	Supermajority        float64   `json:"supermajority"`
+	SupermajorityReached bool      `json:"supermajority_reached"`
+	Level                int       `json:"level"`
+	Timestamp            time.Time `json:"timestamp"`
+}
+
+type ballotResponse struct {
+	Period        int       `json:"period"`
+	PKH           string    `json:"pkh"`
+	Ballot        string    `json:"ballot"`
+	ProposalHash  string    `json:"proposal_hash"`
+	VotingPower   int64     `json:"voting_power"`
+	Unit          string    `json:"unit"`
+	Level         int       `json:"level"`
+	OperationHash string    `json:"operation_hash"`
+	Timestamp     time.Time `json:"timestamp"`
+}
+
+type supporterResponse struct {
+	PKH           string    `json:"pkh"`
+	VotingPower   int64     `json:"voting_power"`
+	Level         int       `json:"level"`
+	OperationHash string    `json:"operation_hash"`
+	Timestamp     time.Time `json:"timestamp"`
+}
+
+type proposalResponse struct {
+	Period       int                  `json:"period"`
+	ProposalHash string               `json:"proposal_hash"`
+	InjectedBy   string               `json:"injected_by,omitempty"`
+	VotingPower  int64                `json:"voting_power"`
+	Unit         string               `json:"unit"`
+	Supporters   []*supporterResponse `json:"supporters"`
+}
+
+type bakerResponse struct {
+	PKH     string              `json:"pkh"`
+	Ballots []*ballotResponse   `json:"ballots"`
+	Upvotes []*proposalResponse `json:"upvotes"`
+}
+
+// handlePeriod returns the current voting period
+func (s *Server) handlePeriod(w http.ResponseWriter, r *http.Request) {
+	s.view(w, func(idx *index) {
+		if idx.current == nil {
+			writeError(w, http.StatusNotFound, "no voting period observed yet")
+			return
+		}
+		p := *idx.current
+		p.Outcome = nil
+		writeJSON(w, http.StatusOK, &p)
+	})
+}
+
+// handleTallies returns the latest tallies of the current ballot period
+func (s *Server) handleTallies(w http.ResponseWriter, r *http.Request) {
+	s.view(w, func(idx *index) {
+		// The tallies of an ended ballot period are not current, they are part of its outcome
+		p := idx.current
+		if p == nil || (p.Kind != "exploration" && p.Kind != "promotion") {
+			writeError(w, http.StatusNotFound, "no ballot period in progress")
+			return
+		}
+
+		var b *models.Ballot
+		for i := len(idx.ballots) - 1; i >= 0 && b == nil; i-- {
+			if idx.ballots[i].Period == p.Index {
+				b = idx.ballots[i]
+			}
+		}
+		if b == nil {
+			writeError(w, http.StatusNotFound, "no ballot observed yet in the current period")
+			return
+		}
+
+		writeJSON(w, http.StatusOK, talliesResponse{
+			Period:               b.Period,
+			Phase:                b.Phase(),
+			ProposalHash:         b.ProposalHash,
+			Yay:                  b.Yay,
+			Nay:                  b.Nay,
+			Pass:                 b.Pass,
+			TotalVotingPower:     int64(b.TotalVotingPower),
+			Unit:                 b.Unit.String(),
+			PercentParticipation: b.PercentParticipation(),
+			PercentYay:           b.CountingPercentYay(),
+			Quorum:               b.Quorum.Percent(),
+			ProjectedQuorum:      b.ProjectedQuorum(),
+			QuorumReached:        b.QuorumReached(),
+			Supermajority:        b.Supermajority,
+			SupermajorityReached: b.SupermajorityReached(),
+			Level:                b.Level,
+			Timestamp:            b.Timestamp,
+		})
+	})
+}
+
+// handlePeriods returns the voting periods observed and the outcome of those which ended
+func (s *Server) handlePeriods(w http.ResponseWriter, r *http.Request) {
+	s.view(w, func(idx *index) {
+		writeJSON(w, http.StatusOK, idx.periods)
+	})
+}
+
+// handlePeriodBallots returns the ballots of a period, /api/periods/{index}/ballots?ballot=yay&pkh=tz1...
+func (s *Server) handlePeriodBallots(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/periods/"), "/"), "/")
+	if len(parts) != 2 || parts[1] != "ballots" {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	period, err := strconv.Atoi(parts[0])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid period index")
+		return
+	}
+
+	filter := r.URL.Query()
+	s.view(w, func(idx *index) {
+		result := []*ballotResponse{}
+		for _, b := range idx.ballots {
+			if b.Period != period ||
+				(filter.Get("ballot") != "" && b.Ballot != filter.Get("ballot")) ||
+				(filter.Get("pkh") != "" && b.PKH != filter.Get("pkh")) {
+				continue
+			}
+			result = append(result, newBallotResponse(b))
+		}
+		writeJSON(w, http.StatusOK, result)
+	})
+}
+
+// handleProposals returns the proposals of a proposal period with their supporters, ?period=N defaults to the latest
+func (s *Server) handleProposals(w http.ResponseWriter, r *http.Request) {
+	period := -1
+	if v := r.URL.Query().Get("period"); v != "" {
+		var err error
+		if period, err = strconv.Atoi(v); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid period index")
+			return
+		}
+	}
+
+	s.view(w, func(idx *index) {
+		if period < 0 && len(idx.proposals) > 0 {
+			period = idx.proposals[len(idx.proposals)-1].Period
+		}
+
+		result := []*proposalResponse{}
+		for _, p := range idx.proposals {
+			if p.Period == period {
+				result = append(result, p)
+			}
+		}
+		writeJSON(w, http.StatusOK, result)
+	})
+}
+
+// handleBaker returns the voting history of a baker, /api/bakers/{pkh}
+func (s *Server) handleBaker(w http.ResponseWriter, r *http.Request) {
+	pkh := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/bakers/"), "/")
+	if pkh == "" || strings.Contains(pkh, "/") {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	s.view(w, func(idx *index) {
+		resp := bakerResponse{
+			PKH:     pkh,
+			Ballots: []*ballotResponse{},
+			Upvotes: []*proposalResponse{},
+		}
+		for _, b := range idx.byBaker[pkh] {
+			resp.Ballots = append(resp.Ballots, newBallotResponse(b))
+		}
+		for _, p := range idx.upvotes {
+			if p.PKH != pkh {
+				continue
+			}
+			upvote := &proposalResponse{
+				Period:       p.Period,
+				ProposalHash: p.ProposalHash,
+				VotingPower:  p.VotingPower,
+				Unit:         p.Unit.String(),
+				Supporters:   []*supporterResponse{newSupporterResponse(p)},
+			}
+			if idx.injections[p] {
+				upvote.InjectedBy = p.PKH
+			}
+			resp.Upvotes = append(resp.Upvotes, upvote)
+		}
+
+		writeJSON(w, http.StatusOK, resp)
+	})
+}
+
+// eventRef returns the block reference recorded with an event
+func eventRef(e *store.Event) models.BlockRef {
+	return models.BlockRef{
+		Level:         e.Level,
+		BlockHash:     e.BlockHash,
+		OperationHash: e.OperationHash,
+		Timestamp:     e.Timestamp,
+	}
+}
+
+func newResultResponse(r *models.BallotResult) *resultResponse {
+	if r == nil {
+		return nil
+	}
+	return &resultResponse{
+		Yay:                  r.Yay,
+		Nay:                  r.Nay,
+		Pass:                 r.Pass,
+		TotalVotingPower:     r.TotalVotingPower,
+		Unit:                 r.Unit.String(),
+		PercentParticipation: r.PercentParticipation(),
+		Quorum:               r.Quorum.Percent(),
+		Supermajority:        r.Supermajority,
+		PercentYay:           r.PercentYay(),
+		Passed:               r.Passed(),
+		FailureReason:        r.FailureReason(),
+	}
+}
+
+func newBallotResponse(b *models.Ballot) *ballotResponse {
+	return &ballotResponse{
+		Period:        b.Period,
+		PKH:           b.PKH,
+		Ballot:        b.Ballot,
+		ProposalHash:  b.ProposalHash,
+		VotingPower:   b.VotingPower,
+		Unit:          b.Unit.String(),
+		Level:         b.Level,
+		OperationHash: b.OperationHash,
+		Timestamp:     b.Timestamp,
+	}
+}
+
+func newSupporterResponse(p *models.Proposal) *supporterResponse {
+	return &supporterResponse{
+		PKH:           p.PKH,
+		VotingPower:   p.VotingPower,
+		Level:         p.Level,
+		OperationHash: p.OperationHash,
+		Timestamp:     p.Timestamp,
+	}
+}
@@ -0,0 +1,748 @@
+package api
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ecadlabs/tezos-bot/models"
+)
+
+const (
+	defaultPageSize = 100
+	maxPageSize     = 1000
+	cursorPrefix    = "cursor:"
+)
+
+type graphQLRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+type indexKey struct{}
+
+// handleGraphQL executes a GraphQL query, sent as a JSON body or with the query, operationName and variables parameters
+func (s *Server) handleGraphQL(w http.ResponseWriter, r *http.Request) {
+	var req graphQLRequest
+	switch r.Method {
+	case http.MethodGet:
+		q := r.URL.Query()
+		req.Query = q.Get("query")
+		req.OperationName = q.Get("operationName")
+		if v := q.Get("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				writeError(w, http.StatusBadRequest, "invalid variables")
+				return
+			}
+		}
+	case http.MethodPost:
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	s.view(w, func(idx *index) {
+		ctx := context.WithValue(r.Context(), indexKey{}, idx)
+		writeJSON(w, http.StatusOK, s.schema.Exec(ctx, req.Query, req.OperationName, req.Variables))
+	})
+}
+
+// page is a slice of a list, as selected by the first and after arguments
+type page struct {
+	total, start, end int
+}
+
+func paginate(total int, first *int32, after *string) (page, error) {
+	start := 0
+	if after != nil {
+		raw, err := base64.StdEncoding.DecodeString(*after)
+		if err != nil || !strings.HasPrefix(string(raw), cursorPrefix) {
+			return page{}, fmt.Errorf("invalid cursor %q", *after)
+		}
+		offset, err := strconv.Atoi(strings.TrimPrefix(string(raw), cursorPrefix))
+		if err != nil || offset < 0 {
+			return page{}, fmt.Errorf("invalid cursor %q", *after)
+		}
+		start = offset + 1
+	}
+
+	size := defaultPageSize
+	if first != nil {
+		if *first < 0 {
+			return page{}, fmt.Errorf("first must not be negative")
+		}
+		size = int(*first)
+	}
+	if size > maxPageSize {
+		size = maxPageSize
+	}
+
+	if start > total {
+		start = total
+	}
+	end := start + size
+	if end > total {
+		end = total
+	}
+	return page{total: total, start: start, end: end}, nil
+}
+
+func (p page) TotalCount() int32 {
+	return int32(p.total)
+}
+
+func (p page) PageInfo() *pageInfo {
+	info := &pageInfo{hasNextPage: p.end < p.total}
+	if p.end > p.start {
+		c := base64.StdEncoding.EncodeToString([]byte(cursorPrefix + strconv.Itoa(p.end-1)))
+		info.endCursor = &c
+	}
+	return info
+}
+
+type pageInfo struct {
+	hasNextPage bool
+	endCursor   *string
+}
+
+func (p *pageInfo) HasNextPage() bool {
+	return p.hasNextPage
+}
+
+func (p *pageInfo) EndCursor() *string {
+	return p.endCursor
+}
+
+type pageArgs struct {
+	First *int32
+	After *string
+}
+
+type ballotArgs struct {
+	Filter *ballotFilter
+	First  *int32
+	After  *string
+}
+
+type ballotFilter struct {
+	Period       *int32
+	Phase        *string
+	Ballot       *string
+	PKH          *string
+	ProposalHash *string
+	FromLevel    *int32
+	ToLevel      *int32
+}
+
+func (f *ballotFilter) match(b *models.Ballot) bool {
+	if f == nil {
+		return true
+	}
+	return (f.Period == nil || int(*f.Period) == b.Period) &&
+		(f.Phase == nil || *f.Phase == b.Phase()) &&
+		(f.Ballot == nil || *f.Ballot == b.Ballot) &&
+		(f.PKH == nil || *f.PKH == b.PKH) &&
+		(f.ProposalHash == nil || *f.ProposalHash == b.ProposalHash) &&
+		(f.FromLevel == nil || b.Level >= int(*f.FromLevel)) &&
+		(f.ToLevel == nil || b.Level <= int(*f.ToLevel))
+}
+
+type bakerFilter struct {
+	Voted   *[]*ballotFilter
+	Upvoted *string
+}
+
+func optionalString(v string) *string {
+	if v == "" {
+		return nil
+	}
+	return &v
+}
+
+func optionalTime(t time.Time) *string {
+	if t.IsZero() {
+		return nil
+	}
+	return optionalString(t.Format(time.RFC3339))
+}
+
+type queryResolver struct{}
+
+func indexFrom(ctx context.Context) *index {
+	return ctx.Value(indexKey{}).(*index)
+}
+
+func (q *queryResolver) Periods(ctx context.Context, args struct {
+	Kind  *string
+	First *int32
+	After *string
+}) (*periodConnection, error) {
+	idx := indexFrom(ctx)
+	periods := []*periodResponse{}
+	for _, p := range idx.periods {
+		if args.Kind == nil || *args.Kind == p.Kind {
+			periods = append(periods, p)
+		}
+	}
+	pg, err := paginate(len(periods), args.First, args.After)
+	if err != nil {
+		return nil, err
+	}
+	conn := &periodConnection{page: pg}
+	for _, p := range periods[pg.start:pg.end] {
+		conn.nodes = append(conn.nodes, &periodResolver{idx: idx, p: p})
+	}
+	return conn, nil
+}
+
+func (q *queryResolver) Period(ctx context.Context, args struct{ Index int32 }) *periodResolver {
+	return indexFrom(ctx).period(int(args.Index))
+}
+
+func (q *queryResolver) Ballots(ctx context.Context, args ballotArgs) (*ballotConnection, error) {
+	idx := indexFrom(ctx)
+	return idx.ballotConnection(idx.ballots, args)
+}
+
+func (q *queryResolver) Proposals(ctx context.Context, args struct {
+	Period *int32
+	Hash   *string
+	First  *int32
+	After  *string
+}) (*proposalConnection, error) {
+	idx := indexFrom(ctx)
+	proposals := []*proposalResponse{}
+	for _, p := range idx.proposals {
+		if (args.Period == nil || int(*args.Period) == p.Period) && (args.Hash == nil || *args.Hash == p.ProposalHash) {
+			proposals = append(proposals, p)
+		}
+	}
+	pg, err := paginate(len(proposals), args.First, args.After)
+	if err != nil {
+		return nil, err
+	}
+	conn := &proposalConnection{page: pg}
+	for _, p := range proposals[pg.start:pg.end] {
+		conn.nodes = append(conn.nodes, &proposalResolver{idx: idx, p: p})
+	}
+	return conn, nil
+}
+
+func (q *queryResolver) Bakers(ctx context.Context, args struct {
+	Filter *bakerFilter
+	First  *int32
+	After  *string
+}) (*bakerConnection, error) {
+	idx := indexFrom(ctx)
+
+	upvoted := make(map[string]map[string]bool)
+	for _, p := range idx.proposals {
+		for _, s := range p.Supporters {
+			if upvoted[s.PKH] == nil {
+				upvoted[s.PKH] = make(map[string]bool)
+			}
+			upvoted[s.PKH][p.ProposalHash] = true
+		}
+	}
+
+	pkhs := []string{}
+	for pkh := range idx.byBaker {
+		pkhs = append(pkhs, pkh)
+	}
+	for pkh := range upvoted {
+		if _, ok := idx.byBaker[pkh]; !ok {
+			pkhs = append(pkhs, pkh)
+		}
+	}
+	sort.Strings(pkhs)
+
+	bakers := []string{}
+	for _, pkh := range pkhs {
+		if args.Filter != nil && args.Filter.Upvoted != nil && !upvoted[pkh][*args.Filter.Upvoted] {
+			continue
+		}
+		if args.Filter != nil && args.Filter.Voted != nil && !idx.votedAll(pkh, *args.Filter.Voted) {
+			continue
+		}
+		bakers = append(bakers, pkh)
+	}
+
+	pg, err := paginate(len(bakers), args.First, args.After)
+	if err != nil {
+		return nil, err
+	}
+	conn := &bakerConnection{page: pg}
+	for _, pkh := range bakers[pg.start:pg.end] {
+		conn.nodes = append(conn.nodes, &bakerResolver{idx: idx, pkh: pkh})
+	}
+	return conn, nil
+}
+
+func (q *queryResolver) Baker(ctx context.Context, args struct{ PKH string }) *bakerResolver {
+	idx := indexFrom(ctx)
+	if _, ok := idx.byBaker[args.PKH]; ok {
+		return &bakerResolver{idx: idx, pkh: args.PKH}
+	}
+	for _, p := range idx.proposals {
+		for _, s := range p.Supporters {
+			if s.PKH == args.PKH {
+				return &bakerResolver{idx: idx, pkh: args.PKH}
+			}
+		}
+	}
+	return nil
+}
+
+func (q *queryResolver) ProtocolActivations(ctx context.Context, args pageArgs) (*protocolActivationConnection, error) {
+	idx := indexFrom(ctx)
+	pg, err := paginate(len(idx.activations), args.First, args.After)
+	if err != nil {
+		return nil, err
+	}
+	conn := &protocolActivationConnection{page: pg}
+	for _, a := range idx.activations[pg.start:pg.end] {
+		conn.nodes = append(conn.nodes, &activationResolver{idx: idx, a: a})
+	}
+	return conn, nil
+}
+
+func (idx *index) period(i int) *periodResolver {
+	p, ok := idx.byPeriod[i]
+	if !ok {
+		return nil
+	}
+	return &periodResolver{idx: idx, p: p}
+}
+
+// votedAll returns true if the baker cast a ballot matching each of the filters
+func (idx *index) votedAll(pkh string, filters []*ballotFilter) bool {
+	for _, f := range filters {
+		found := false
+		for _, b := range idx.byBaker[pkh] {
+			if f.match(b) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func (idx *index) ballotConnection(ballots []*models.Ballot, args ballotArgs) (*ballotConnection, error) {
+	matching := []*models.Ballot{}
+	for _, b := range ballots {
+		if args.Filter.match(b) {
+			matching = append(matching, b)
+		}
+	}
+	pg, err := paginate(len(matching), args.First, args.After)
+	if err != nil {
+		return nil, err
+	}
+	conn := &ballotConnection{page: pg}
+	for _, b := range matching[pg.start:pg.end] {
+		conn.nodes = append(conn.nodes, &ballotResolver{idx: idx, b: b})
+	}
+	return conn, nil
+}
+
+func (idx *index) proposalsOf(match func(p *proposalResponse) bool) []*proposalResolver {
+	result := []*proposalResolver{}
+	for _, p := range idx.proposals {
+		if match(p) {
+			result = append(result, &proposalResolver{idx: idx, p: p})
+		}
+	}
+	return result
+}
+
+type periodConnection struct {
+	page
+	nodes []*periodResolver
+}
+
+func (c *periodConnection) Nodes() []*periodResolver {
+	return c.nodes
+}
+
+type ballotConnection struct {
+	page
+	nodes []*ballotResolver
+}
+
+func (c *ballotConnection) Nodes() []*ballotResolver {
+	return c.nodes
+}
+
+type proposalConnection struct {
+	page
+	nodes []*proposalResolver
+}
+
+func (c *proposalConnection) Nodes() []*proposalResolver {
+	return c.nodes
+}
+
+type bakerConnection struct {
+	page
+	nodes []*bakerResolver
+}
+
+func (c *bakerConnection) Nodes() []*bakerResolver {
+	return c.nodes
+}
+
+type protocolActivationConnection struct {
+	page
+	nodes []*activationResolver
+}
+
+func (c *protocolActivationConnection) Nodes() []*activationResolver {
+	return c.nodes
+}
+
+type periodResolver struct {
+	idx *index
+	p   *periodResponse
+}
+
+func (r *periodResolver) Index() int32 {
+	return int32(r.p.Index)
+}
+
+func (r *periodResolver) Kind() string {
+	return r.p.Kind
+}
+
+func (r *periodResolver) StartLevel() int32 {
+	return int32(r.p.StartLevel)
+}
+
+func (r *periodResolver) EndLevel() int32 {
+	return int32(r.p.EndLevel)
+}
+
+func (r *periodResolver) EstimatedEnd() *string {
+	return optionalTime(r.p.EstimatedEnd)
+}
+
+func (r *periodResolver) ProposalHash() *string {
+	return optionalString(r.p.ProposalHash)
+}
+
+func (r *periodResolver) Outcome() *outcomeResolver {
+	if r.p.Outcome == nil {
+		return nil
+	}
+	return &outcomeResolver{o: r.p.Outcome}
+}
+
+func (r *periodResolver) Ballots(args ballotArgs) (*ballotConnection, error) {
+	ballots := []*models.Ballot{}
+	for _, b := range r.idx.ballots {
+		if b.Period == r.p.Index {
+			ballots = append(ballots, b)
+		}
+	}
+	return r.idx.ballotConnection(ballots, args)
+}
+
+func (r *periodResolver) Proposals() []*proposalResolver {
+	return r.idx.proposalsOf(func(p *proposalResponse) bool { return p.Period == r.p.Index })
+}
+
+type outcomeResolver struct {
+	o *outcome
+}
+
+func (r *outcomeResolver) ToIndex() int32 {
+	return int32(r.o.ToIndex)
+}
+
+func (r *outcomeResolver) ToKind() string {
+	return r.o.ToKind
+}
+
+func (r *outcomeResolver) ProposalHash() *string {
+	return optionalString(r.o.ProposalHash)
+}
+
+func (r *outcomeResolver) Result() *resultResolver {
+	if r.o.Result == nil {
+		return nil
+	}
+	return &resultResolver{r: r.o.Result}
+}
+
+type resultResolver struct {
+	r *resultResponse
+}
+
+func (r *resultResolver) Yay() float64 {
+	return float64(r.r.Yay)
+}
+
+func (r *resultResolver) Nay() float64 {
+	return float64(r.r.Nay)
+}
+
+func (r *resultResolver) Pass() float64 {
+	return float64(r.r.Pass)
+}
+
+func (r *resultResolver) TotalVotingPower() float64 {
+	return float64(r.r.TotalVotingPower)
+}
+
+func (r *resultResolver) Unit() string {
+	return r.r.Unit
+}
+
+func (r *resultResolver) PercentParticipation() float64 {
+	return r.r.PercentParticipation
+}
+
+func (r *resultResolver) Quorum() float64 {
+	return r.r.Quorum
+}
+
+func (r *resultResolver) Supermajority() float64 {
+	return r.r.Supermajority
+}
+
+func (r *resultResolver) PercentYay() float64 {
+	return r.r.PercentYay
+}
+
+func (r *resultResolver) Passed() bool {
+	return r.r.Passed
+}
+
+func (r *resultResolver) FailureReason() *string {
+	return optionalString(r.r.FailureReason)
+}
+
+type ballotResolver struct {
+	idx *index
+	b   *models.Ballot
+}
+
+func (r *ballotResolver) PKH() string {
+	return r.b.PKH
+}
+
+func (r *ballotResolver) Baker() *bakerResolver {
+	return &bakerResolver{idx: r.idx, pkh: r.b.PKH}
+}
+
+func (r *ballotResolver) Ballot() string {
+	return r.b.Ballot
+}
+
+func (r *ballotResolver) Phase() string {
+	return r.b.Phase()
+}
+
+func (r *ballotResolver) PeriodIndex() int32 {
+	return int32(r.b.Period)
+}
+
+func (r *ballotResolver) Period() *periodResolver {
+	return r.idx.period(r.b.Period)
+}
+
+func (r *ballotResolver) ProposalHash() string {
+	return r.b.ProposalHash
+}
+
+func (r *ballotResolver) VotingPower() float64 {
+	return float64(r.b.VotingPower)
+}
+
+func (r *ballotResolver) Unit() string {
+	return r.b.Unit.String()
+}
+
+func (r *ballotResolver) Level() int32 {
+	return int32(r.b.Level)
+}
+
+func (r *ballotResolver) OperationHash() string {
+	return r.b.OperationHash
+}
+
+func (r *ballotResolver) Timestamp() string {
+	return r.b.Timestamp.Format(time.RFC3339)
+}
+
+type proposalResolver struct {
+	idx *index
+	p   *proposalResponse
+}
+
+func (r *proposalResolver) Hash() string {
+	return r.p.ProposalHash
+}
+
+func (r *proposalResolver) PeriodIndex() int32 {
+	return int32(r.p.Period)
+}
+
+func (r *proposalResolver) Period() *periodResolver {
+	return r.idx.period(r.p.Period)
+}
+
+func (r *proposalResolver) InjectedBy() *bakerResolver {
+	if r.p.InjectedBy == "" {
+		return nil
+	}
+	return &bakerResolver{idx: r.idx, pkh: r.p.InjectedBy}
+}
+
+func (r *proposalResolver) VotingPower() float64 {
+	return float64(r.p.VotingPower)
+}
+
+func (r *proposalResolver) Unit() string {
+	return r.p.Unit
+}
+
+func (r *proposalResolver) Upvotes() []*upvoteResolver {
+	upvotes := make([]*upvoteResolver, len(r.p.Supporters))
+	for i, s := range r.p.Supporters {
+		upvotes[i] = &upvoteResolver{idx: r.idx, s: s, p: r.p}
+	}
+	return upvotes
+}
+
+// Ballots returns the ballots cast on the proposal in the periods following its selection
+func (r *proposalResolver) Ballots(args ballotArgs) (*ballotConnection, error) {
+	ballots := []*models.Ballot{}
+	for _, b := range r.idx.ballots {
+		if b.ProposalHash == r.p.ProposalHash && b.Period > r.p.Period {
+			ballots = append(ballots, b)
+		}
+	}
+	return r.idx.ballotConnection(ballots, args)
+}
+
+func (r *proposalResolver) Activation() *activationResolver {
+	for _, a := range r.idx.activations {
+		if a.protocol == r.p.ProposalHash && (a.period == nil || a.period.Index > r.p.Period) {
+			return &activationResolver{idx: r.idx, a: a}
+		}
+	}
+	return nil
+}
+
+type upvoteResolver struct {
+	idx *index
+	s   *supporterResponse
+	p   *proposalResponse
+}
+
+func (r *upvoteResolver) Baker() *bakerResolver {
+	return &bakerResolver{idx: r.idx, pkh: r.s.PKH}
+}
+
+func (r *upvoteResolver) Proposal() *proposalResolver {
+	return &proposalResolver{idx: r.idx, p: r.p}
+}
+
+func (r *upvoteResolver) VotingPower() float64 {
+	return float64(r.s.VotingPower)
+}
+
+func (r *upvoteResolver) Level() int32 {
+	return int32(r.s.Level)
+}
+
+func (r *upvoteResolver) OperationHash() string {
+	return r.s.OperationHash
+}
+
+func (r *upvoteResolver) Timestamp() string {
+	return r.s.Timestamp.Format(time.RFC3339)
+}
+
+type bakerResolver struct {
+	idx *index
+	pkh string
+}
+
+func (r *bakerResolver) PKH() string {
+	return r.pkh
+}
+
+func (r *bakerResolver) Ballots(args ballotArgs) (*ballotConnection, error) {
+	return r.idx.ballotConnection(r.idx.byBaker[r.pkh], args)
+}
+
+func (r *bakerResolver) Upvotes() []*upvoteResolver {
+	upvotes := []*upvoteResolver{}
+	for _, p := range r.idx.proposals {
+		for _, s := range p.Supporters {
+			if s.PKH == r.pkh {
+				upvotes = append(upvotes, &upvoteResolver{idx: r.idx, s: s, p: p})
+			}
+		}
+	}
+	return upvotes
+}
+
+type activationResolver struct {
+	idx *index
+	a   *activation
+}
+
+func (r *activationResolver) Protocol() string {
+	return r.a.protocol
+}
+
+func (r *activationResolver) Period() *periodResolver {
+	if r.a.period == nil {
+		return nil
+	}
+	return &periodResolver{idx: r.idx, p: r.a.period}
+}
+
+func (r *activationResolver) ActivationLevel() *int32 {
+	if r.a.period == nil {
+		return nil
+	}
+	level := int32(r.a.period.EndLevel + 1)
+	return &level
+}
+
+func (r *activationResolver) EstimatedActivation() *string {
+	if r.a.period == nil {
+		return nil
+	}
+	return optionalTime(r.a.period.EstimatedEnd)
+}
+
+func (r *activationResolver) Activated() bool {
+	return !r.a.observedAt.IsZero()
+}
+
+func (r *activationResolver) ObservedAt() *string {
+	return optionalTime(r.a.observedAt)
+}
+
+func (r *activationResolver) Proposals() []*proposalResolver {
+	return r.idx.proposalsOf(func(p *proposalResponse) bool { return p.ProposalHash == r.a.protocol })
+}
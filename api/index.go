@@ -0,0 +1,263 @@
+package api
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/ecadlabs/tezos-bot/models"
+	"github.com/ecadlabs/tezos-bot/store"
+)
+
+// index is the governance history, read from the event store once and kept up to date as the events are recorded
+type index struct {
+	// periods are sorted by index, current is the observed voting period with the highest index
+	periods  []*periodResponse
+	byPeriod map[int]*periodResponse
+	current  *periodResponse
+
+	// ballots and upvotes are in the order they were cast, once each
+	ballots     []*models.Ballot
+	byBaker     map[string][]*models.Ballot
+	seenBallots map[string]bool
+	upvotes     []*models.Proposal
+	injections  map[*models.Proposal]bool
+	seenUpvotes map[string]*models.Proposal
+
+	// proposals group the upvotes by period and proposal, the proposals of a period are sorted by voting power
+	proposals  []*proposalResponse
+	byProposal map[proposalKey]*proposalResponse
+
+	activations []*activation
+	byProtocol  map[string]*activation
+}
+
+type proposalKey struct {
+	period int
+	hash   string
+}
+
+// activation is a protocol scheduled for activation by an adoption period or seen activating
+type activation struct {
+	protocol   string
+	period     *periodResponse
+	observedAt time.Time
+}
+
+func newIndex() *index {
+	return &index{
+		byPeriod:    make(map[int]*periodResponse),
+		byBaker:     make(map[string][]*models.Ballot),
+		seenBallots: make(map[string]bool),
+		injections:  make(map[*models.Proposal]bool),
+		seenUpvotes: make(map[string]*models.Proposal),
+		byProposal:  make(map[proposalKey]*proposalResponse),
+		byProtocol:  make(map[string]*activation),
+	}
+}
+
+// loadIndex builds the index from every event recorded so far
+func loadIndex(events EventReader) (*index, error) {
+	recorded, err := events.List(store.Filter{})
+	if err != nil {
+		return nil, err
+	}
+
+	idx := newIndex()
+	for _, e := range recorded {
+		event, err := decodePayload(e)
+		if err != nil {
+			return nil, err
+		}
+		ref := eventRef(e)
+		// Protocol changes recorded by older versions have no block timestamp
+		if ref.Timestamp.IsZero() {
+			ref.Timestamp = e.RecordedAt
+		}
+		idx.add(e.Kind, ref, event)
+	}
+	return idx, nil
+}
+
+// decodePayload returns the payload of the kinds of events the index is built from, nil for the others
+func decodePayload(e *store.Event) (interface{}, error) {
+	var event interface{}
+	switch e.Kind {
+	case models.EventVotingPeriod:
+		event = &models.VotingPeriod{}
+	case models.EventPeriodTransition:
+		event = &models.PeriodTransition{}
+	case models.EventBallot:
+		event = &models.Ballot{}
+	case models.EventBallotDigest:
+		event = &models.BallotDigest{}
+	case models.EventProposalInjection, models.EventProposalUpvote:
+		event = &models.Proposal{}
+	case models.EventProtocol:
+		var protocol string
+		if err := json.Unmarshal(e.Payload, &protocol); err != nil {
+			return nil, err
+		}
+		return protocol, nil
+	default:
+		return nil, nil
+	}
+	if err := json.Unmarshal(e.Payload, event); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// add indexes an event, events recorded again by a backfill replaying a period are only counted once
+func (idx *index) add(kind string, ref models.BlockRef, event interface{}) {
+	switch e := event.(type) {
+	case *models.VotingPeriod:
+		idx.addPeriod(e)
+	case *models.PeriodTransition:
+		idx.addTransition(e)
+	case *models.Ballot:
+		b := *e
+		b.BlockRef = ref
+		idx.addBallot(&b)
+	case *models.BallotDigest:
+		for _, b := range e.Ballots {
+			idx.addBallot(b)
+		}
+	case *models.Proposal:
+		p := *e
+		p.BlockRef = ref
+		idx.addUpvote(&p, kind == models.EventProposalInjection)
+	case string:
+		if kind == models.EventProtocol {
+			idx.addProtocol(e, ref.Timestamp)
+		}
+	}
+}
+
+// periodAt returns the period index, creating it when it was not seen yet
+func (idx *index) periodAt(i int) *periodResponse {
+	if p, ok := idx.byPeriod[i]; ok {
+		return p
+	}
+	p := &periodResponse{Index: i}
+	idx.byPeriod[i] = p
+	at := sort.Search(len(idx.periods), func(j int) bool { return idx.periods[j].Index > i })
+	idx.periods = append(idx.periods, nil)
+	copy(idx.periods[at+1:], idx.periods[at:])
+	idx.periods[at] = p
+	return p
+}
+
+func (idx *index) addPeriod(period *models.VotingPeriod) {
+	p := idx.periodAt(period.Index)
+	p.Kind = period.Kind
+	p.StartLevel = period.StartLevel
+	p.EndLevel = period.EndLevel
+	p.EstimatedEnd = period.EstimatedEnd
+	p.ProposalHash = period.ProposalHash
+	if idx.current == nil || p.Index >= idx.current.Index {
+		idx.current = p
+	}
+
+	if p.Kind == "adoption" && p.ProposalHash != "" {
+		idx.activation(p.ProposalHash).period = p
+	}
+}
+
+func (idx *index) addTransition(t *models.PeriodTransition) {
+	p := idx.periodAt(t.FromIndex)
+	if p.Kind == "" {
+		// The bot only saw the period end
+		p.Kind = t.FromKind
+	}
+	p.Outcome = &outcome{
+		ToIndex:      t.ToIndex,
+		ToKind:       t.ToKind,
+		ProposalHash: t.ProposalHash,
+		Result:       newResultResponse(t.Result),
+	}
+}
+
+func (idx *index) addBallot(b *models.Ballot) {
+	key := b.OperationHash + b.PKH
+	if idx.seenBallots[key] {
+		return
+	}
+	idx.seenBallots[key] = true
+	idx.ballots = insertBallot(idx.ballots, b)
+	idx.byBaker[b.PKH] = insertBallot(idx.byBaker[b.PKH], b)
+}
+
+func (idx *index) addUpvote(p *models.Proposal, injection bool) {
+	key := p.OperationHash + p.ProposalHash
+	if seen, ok := idx.seenUpvotes[key]; ok {
+		if injection && !idx.injections[seen] {
+			idx.injections[seen] = true
+			idx.byProposal[proposalKey{period: seen.Period, hash: seen.ProposalHash}].InjectedBy = seen.PKH
+		}
+		return
+	}
+	idx.seenUpvotes[key] = p
+	idx.injections[p] = injection
+
+	at := sort.Search(len(idx.upvotes), func(i int) bool { return idx.upvotes[i].Level > p.Level })
+	idx.upvotes = append(idx.upvotes, nil)
+	copy(idx.upvotes[at+1:], idx.upvotes[at:])
+	idx.upvotes[at] = p
+
+	k := proposalKey{period: p.Period, hash: p.ProposalHash}
+	resp, ok := idx.byProposal[k]
+	if !ok {
+		resp = &proposalResponse{
+			Period:       p.Period,
+			ProposalHash: p.ProposalHash,
+			Unit:         p.Unit.String(),
+			Supporters:   []*supporterResponse{},
+		}
+		idx.byProposal[k] = resp
+		idx.proposals = append(idx.proposals, resp)
+	}
+	if injection {
+		resp.InjectedBy = p.PKH
+	}
+	resp.VotingPower += p.VotingPower
+	at = sort.Search(len(resp.Supporters), func(i int) bool { return resp.Supporters[i].Level > p.Level })
+	resp.Supporters = append(resp.Supporters, nil)
+	copy(resp.Supporters[at+1:], resp.Supporters[at:])
+	resp.Supporters[at] = newSupporterResponse(p)
+
+	sort.SliceStable(idx.proposals, func(i, j int) bool {
+		if idx.proposals[i].Period != idx.proposals[j].Period {
+			return idx.proposals[i].Period < idx.proposals[j].Period
+		}
+		return idx.proposals[i].VotingPower > idx.proposals[j].VotingPower
+	})
+}
+
+func (idx *index) addProtocol(protocol string, observedAt time.Time) {
+	// The bot may not have seen the adoption period
+	a := idx.activation(protocol)
+	if a.observedAt.IsZero() {
+		a.observedAt = observedAt
+	}
+}
+
+// activation returns the activation of protocol, creating it when it was not seen yet
+func (idx *index) activation(protocol string) *activation {
+	a, ok := idx.byProtocol[protocol]
+	if !ok {
+		a = &activation{protocol: protocol}
+		idx.byProtocol[protocol] = a
+		idx.activations = append(idx.activations, a)
+	}
+	return a
+}
+
+// insertBallot inserts b after the ballots cast at or before its level
+func insertBallot(ballots []*models.Ballot, b *models.Ballot) []*models.Ballot {
+	at := sort.Search(len(ballots), func(i int) bool { return ballots[i].Level > b.Level })
+	ballots = append(ballots, nil)
+	copy(ballots[at+1:], ballots[at:])
+	ballots[at] = b
+	return ballots
+}
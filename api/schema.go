@@ -0,0 +1,157 @@
+package api
+
+// schema describes the governance history served on /api/graphql. Voting powers are floats as they can exceed
+// the 32 bits of GraphQL integers when expressed in mutez, lists are paginated with first and an opaque after cursor
+const schema = `
+schema {
+	query: Query
+}
+
+type Query {
+	periods(kind: String, first: Int, after: String): PeriodConnection!
+	period(index: Int!): Period
+	ballots(filter: BallotFilter, first: Int, after: String): BallotConnection!
+	proposals(period: Int, hash: String, first: Int, after: String): ProposalConnection!
+	bakers(filter: BakerFilter, first: Int, after: String): BakerConnection!
+	baker(pkh: String!): Baker
+	protocolActivations(first: Int, after: String): ProtocolActivationConnection!
+}
+
+input BallotFilter {
+	period: Int
+	phase: String
+	ballot: String
+	pkh: String
+	proposalHash: String
+	fromLevel: Int
+	toLevel: Int
+}
+
+input BakerFilter {
+	# voted only keeps the bakers who cast a ballot matching each of the filters
+	voted: [BallotFilter!]
+	# upvoted only keeps the bakers who upvoted the proposal
+	upvoted: String
+}
+
+type PageInfo {
+	hasNextPage: Boolean!
+	endCursor: String
+}
+
+type PeriodConnection {
+	totalCount: Int!
+	pageInfo: PageInfo!
+	nodes: [Period!]!
+}
+
+type BallotConnection {
+	totalCount: Int!
+	pageInfo: PageInfo!
+	nodes: [Ballot!]!
+}
+
+type ProposalConnection {
+	totalCount: Int!
+	pageInfo: PageInfo!
+	nodes: [Proposal!]!
+}
+
+type BakerConnection {
+	totalCount: Int!
+	pageInfo: PageInfo!
+	nodes: [Baker!]!
+}
+
+type ProtocolActivationConnection {
+	totalCount: Int!
+	pageInfo: PageInfo!
+	nodes: [ProtocolActivation!]!
+}
+
+type Period {
+	index: Int!
+	kind: String!
+	startLevel: Int!
+	endLevel: Int!
+	estimatedEnd: String
+	proposalHash: String
+	outcome: PeriodOutcome
+	ballots(filter: BallotFilter, first: Int, after: String): BallotConnection!
+	proposals: [Proposal!]!
+}
+
+type PeriodOutcome {
+	toIndex: Int!
+	toKind: String!
+	proposalHash: String
+	result: BallotResult
+}
+
+type BallotResult {
+	yay: Float!
+	nay: Float!
+	pass: Float!
+	totalVotingPower: Float!
+	unit: String!
+	percentParticipation: Float!
+	quorum: Float!
+	supermajority: Float!
+	percentYay: Float!
+	passed: Boolean!
+	failureReason: String
+}
+
+type Ballot {
+	pkh: String!
+	baker: Baker!
+	ballot: String!
+	phase: String!
+	periodIndex: Int!
+	period: Period
+	proposalHash: String!
+	votingPower: Float!
+	unit: String!
+	level: Int!
+	operationHash: String!
+	timestamp: String!
+}
+
+type Proposal {
+	hash: String!
+	periodIndex: Int!
+	period: Period
+	injectedBy: Baker
+	votingPower: Float!
+	unit: String!
+	upvotes: [Upvote!]!
+	ballots(filter: BallotFilter, first: Int, after: String): BallotConnection!
+	activation: ProtocolActivation
+}
+
+type Upvote {
+	baker: Baker!
+	proposal: Proposal!
+	votingPower: Float!
+	level: Int!
+	operationHash: String!
+	timestamp: String!
+}
+
+type Baker {
+	pkh: String!
+	ballots(filter: BallotFilter, first: Int, after: String): BallotConnection!
+	upvotes: [Upvote!]!
+}
+
+type ProtocolActivation {
+	protocol: String!
+	# period is the adoption period the activation was scheduled by, unknown when only the protocol change was seen
+	period: Period
+	activationLevel: Int
+	estimatedActivation: String
+	activated: Boolean!
+	observedAt: String
+	proposals: [Proposal!]!
+}
+`
@@ -0,0 +1,144 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ecadlabs/tezos-bot/models"
+	"github.com/ecadlabs/tezos-bot/store"
+	graphql "github.com/graph-gophers/graphql-go"
+)
+
+// APIConfig interface with method necessary to obtain api server configurable parameter
+type APIConfig interface {
+	GetAPIAddr() string
+}
+
+// EventReader interface with method necessary to read the recorded events
+type EventReader interface {
+	List(f store.Filter) ([]*store.Event, error)
+}
+
+// Server exposes the governance state recorded in the event store as read only JSON endpoints
+type Server struct {
+	events EventReader
+	// idx is loaded from events on the first request, then kept up to date by Send
+	mu     sync.RWMutex
+	idx    *index
+	schema *graphql.Schema
+	mux    *http.ServeMux
+	server *http.Server
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// NewServer create a new api Server, events may be nil to only serve the handlers registered with Handle
+func NewServer(config APIConfig, events EventReader) *Server {
+	s := &Server{
+		events: events,
+		mux:    http.NewServeMux(),
+	}
+
+	if events != nil {
+		s.mux.HandleFunc("/api/period", s.handlePeriod)
+		s.mux.HandleFunc("/api/tallies", s.handleTallies)
+		s.mux.HandleFunc("/api/periods", s.handlePeriods)
+		s.mux.HandleFunc("/api/periods/", s.handlePeriodBallots)
+		s.mux.HandleFunc("/api/proposals", s.handleProposals)
+		s.mux.HandleFunc("/api/bakers/", s.handleBaker)
+
+		s.schema = graphql.MustParseSchema(schema, &queryResolver{})
+		s.mux.HandleFunc("/api/graphql", s.handleGraphQL)
+	}
+
+	s.server = &http.Server{
+		Addr:    config.GetAPIAddr(),
+		Handler: s.mux,
+	}
+
+	return s
+}
+
+// Handle registers an additional handler on the api server, it must be called before Start
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Send adds an event to the index once it has been recorded, the server must be registered as a sink of the
+// service recording the events
+func (s *Server) Send(kind string, ref models.BlockRef, event interface{}) error {
+	if ref.Timestamp.IsZero() {
+		ref.Timestamp = time.Now().UTC()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Until the index is loaded the events are read from the store
+	if s.idx != nil {
+		s.idx.add(kind, ref, event)
+	}
+	return nil
+}
+
+// view calls fn with the index read locked, loading it first if needed, and answers with an error when it
+// can not be loaded
+func (s *Server) view(w http.ResponseWriter, fn func(idx *index)) {
+	s.mu.RLock()
+	loaded := s.idx != nil
+	s.mu.RUnlock()
+
+	if !loaded {
+		s.mu.Lock()
+		if s.idx == nil {
+			idx, err := loadIndex(s.events)
+			if err != nil {
+				s.mu.Unlock()
+				writeError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+			s.idx = idx
+		}
+		s.mu.Unlock()
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	fn(s.idx)
+}
+
+// Start serves the api endpoints in the background
+func (s *Server) Start() {
+	go func() {
+		log.Printf("API server listening on %s\n", s.server.Addr)
+		if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Printf("API server stopped because of error: %s\n", err.Error())
+		}
+	}()
+}
+
+// Stop stop the api server
+func (s *Server) Stop() error {
+	return s.server.Close()
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Unable to write api response: %s\n", err.Error())
+	}
+}
+
+func writeError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, errorResponse{Error: msg})
+}
@@ -1,25 +1,89 @@
 package config
 
 import (
+	"fmt"
 	"io/ioutil"
+	"net/url"
+	"strings"
+	"time"
 
 	yaml "gopkg.in/yaml.v2"
 )
 
 // Config struct containing all configurable parameter for the tezos bot
 type Config struct {
-	RPCURL                   string `yaml:"rpc_url"`
-	TwitterAccessToken       string `yaml:"twitter_access_token"`
-	TwitterAccessTokenSecret string `yaml:"twitter_access_token_secret"`
-	TwitterConsummerID       string `yaml:"twitter_consummer_id"`
-	TwitterConsummerKey      string `yaml:"twitter_consummer_key"`
-	ChainID                  string `yaml:"chain"`
-	RetryCount               int    `yaml:"retry_count"`
-	History                  bool   `yaml:"history"`
-	HistoryStartingBlock     int    `yaml:"history_starting_block"`
-	MonitorVote              bool   `yaml:"monitor_vote"`
-	MonitorProtocol          bool   `yaml:"monitor_protocol"`
-	MonitorProposal          bool   `yaml:"monitor_proposal"`
+	RPCURL                   string          `yaml:"rpc_url"`
+	RPCURLs                  []string        `yaml:"rpc_urls"`
+	MaxHeadLag               int             `yaml:"max_head_lag"`
+	CrossCheckHead           bool            `yaml:"cross_check_head"`
+	NodeCheckInterval        time.Duration   `yaml:"node_check_interval"`
+	TwitterAccessToken       string          `yaml:"twitter_access_token"`
+	TwitterAccessTokenSecret string          `yaml:"twitter_access_token_secret"`
+	TwitterConsummerID       string          `yaml:"twitter_consummer_id"`
+	TwitterConsummerKey      string          `yaml:"twitter_consummer_key"`
+	ChainID                  string          `yaml:"chain"`
+	RetryCount               int             `yaml:"retry_count"`
+	RetryMaxInterval         time.Duration   `yaml:"retry_max_interval"`
+	History                  bool            `yaml:"history"`
+	HistoryStartingBlock     int             `yaml:"history_starting_block"`
+	HistoryEndingBlock       int             `yaml:"history_ending_block"`
+	HistoryWorkers           int             `yaml:"history_workers"`
+	HistoryRate              float64         `yaml:"history_rate"`
+	MonitorVote              bool            `yaml:"monitor_vote"`
+	MonitorProtocol          bool            `yaml:"monitor_protocol"`
+	MonitorProposal          bool            `yaml:"monitor_proposal"`
+	MonitorPeriod            bool            `yaml:"monitor_period"`
+	MonitorReport            bool            `yaml:"monitor_report"`
+	BallotDigestInterval     time.Duration   `yaml:"ballot_digest_interval"`
+	BallotDigestCycle        bool            `yaml:"ballot_digest_cycle"`
+	BallotDigestThreshold    float64         `yaml:"ballot_digest_threshold"`
+	MonitorMilestone         bool            `yaml:"monitor_milestone"`
+	ParticipationMilestones  []float64       `yaml:"participation_milestones"`
+	StateFile                string          `yaml:"state_file"`
+	MonitorNonVoters         bool            `yaml:"monitor_non_voters"`
+	NonVotersEveryCycle      bool            `yaml:"non_voters_every_cycle"`
+	NonVotersTop             int             `yaml:"non_voters_top"`
+	MonitorLeaderboard       bool            `yaml:"monitor_leaderboard"`
+	EventStore               string          `yaml:"event_store"`
+	ActivationCountdown      []time.Duration `yaml:"activation_countdown"`
+	Supermajority            float64         `yaml:"supermajority"`
+	HealthAddr               string          `yaml:"health_addr"`
+	APIAddr                  string          `yaml:"api_addr"`
+	StreamBuffer             int             `yaml:"stream_buffer"`
+	StreamFile               string          `yaml:"stream_file"`
+	ReadinessTimeout         time.Duration   `yaml:"readiness_timeout"`
+	AdminAddr                string          `yaml:"admin_addr"`
+	AdminToken               string          `yaml:"admin_token"`
+	AdminUser                string          `yaml:"admin_user"`
+	AdminPassword            string          `yaml:"admin_password"`
+
+	// Schedules are the announcements posted at given levels of the voting periods or times
+	Schedules []Schedule `yaml:"schedules"`
+
+	// Mentions maps the addresses of the bakers who opted in to be mentioned to their handles
+	Mentions map[string]Mention `yaml:"mentions"`
+}
+
+// Mention holds the handles a baker can be mentioned with
+type Mention struct {
+	Twitter string `yaml:"twitter"`
+}
+
+// Schedule is an announcement rendered from a template when one of its triggers is reached, exactly one trigger must be set
+type Schedule struct {
+	Name     string            `yaml:"name"`
+	Template string            `yaml:"template"`
+	Vars     map[string]string `yaml:"vars"`
+	// BlocksBeforePeriodEnd fires once per voting period that many blocks before its end
+	BlocksBeforePeriodEnd int `yaml:"blocks_before_period_end"`
+	// PeriodKinds restricts blocks_before_period_end to these kinds of voting period, empty matches them all
+	PeriodKinds []string `yaml:"period_kinds"`
+	// Activation fires on the first block of the protocol adopted at the end of an adoption period
+	Activation bool `yaml:"activation"`
+	// Level fires once at the given level
+	Level int `yaml:"level"`
+	// Cron fires on the wall clock, a five fields cron expression evaluated in UTC
+	Cron string `yaml:"cron"`
 }
 
 // GetHistoryStartingBlock return the starting block from which the bot should start monitring
@@ -27,9 +91,49 @@ func (c Config) GetHistoryStartingBlock() int {
 	return c.HistoryStartingBlock
 }
 
-// GetRPCURL returns the tezos rpc endpoint
-func (c Config) GetRPCURL() string {
-	return c.RPCURL
+// GetHistoryEndingBlock return the last block the bot should replay, 0 replays until the current head
+func (c Config) GetHistoryEndingBlock() int {
+	return c.HistoryEndingBlock
+}
+
+// GetHistoryWorkers return the number of blocks fetched in parallel while replaying history
+func (c Config) GetHistoryWorkers() int {
+	return c.HistoryWorkers
+}
+
+// GetHistoryRate return the maximum number of blocks fetched per second while replaying history, 0 is unlimited
+func (c Config) GetHistoryRate() float64 {
+	return c.HistoryRate
+}
+
+// GetRPCURLs returns the tezos rpc endpoints by order of preference, rpc_url is kept as the preferred one when set
+func (c Config) GetRPCURLs() []string {
+	if c.RPCURL == "" {
+		return c.RPCURLs
+	}
+
+	urls := []string{c.RPCURL}
+	for _, url := range c.RPCURLs {
+		if url != c.RPCURL {
+			urls = append(urls, url)
+		}
+	}
+	return urls
+}
+
+// GetMaxHeadLag returns how many blocks the rpc node in use can fall behind the others before failing over
+func (c Config) GetMaxHeadLag() int {
+	return c.MaxHeadLag
+}
+
+// IsCrossCheckHead returns true if blocks should be confirmed by the other rpc nodes before being trusted
+func (c Config) IsCrossCheckHead() bool {
+	return c.CrossCheckHead
+}
+
+// GetNodeCheckInterval returns how often the health of the rpc nodes is checked
+func (c Config) GetNodeCheckInterval() time.Duration {
+	return c.NodeCheckInterval
 }
 
 // IsHistory returns true if listener should read history
@@ -37,11 +141,16 @@ func (c Config) IsHistory() bool {
 	return c.History
 }
 
-// GetRetryCount returns the max retry attempt to connect to tezos node
+// GetRetryCount returns the max retry attempt to connect to tezos node, 0 retries forever
 func (c Config) GetRetryCount() int {
 	return c.RetryCount
 }
 
+// GetRetryMaxInterval returns the maximum delay between two attempts to connect to tezos node
+func (c Config) GetRetryMaxInterval() time.Duration {
+	return c.RetryMaxInterval
+}
+
 // GetChainID returns the chain ID
 func (c Config) GetChainID() string {
 	return c.ChainID
@@ -62,6 +171,103 @@ func (c Config) IsMonitorProposal() bool {
 	return c.MonitorProposal
 }
 
+// IsMonitorPeriod returns true if should monitor voting period changes
+func (c Config) IsMonitorPeriod() bool {
+	return c.MonitorPeriod
+}
+
+// IsMonitorReport returns true if should publish a final report at the end of ballot periods
+func (c Config) IsMonitorReport() bool {
+	return c.MonitorReport
+}
+
+// GetBallotDigestInterval returns how long ballots are aggregated into a digest, 0 publishes them one by one
+func (c Config) GetBallotDigestInterval() time.Duration {
+	return c.BallotDigestInterval
+}
+
+// IsBallotDigestCycle returns true if ballots are aggregated into a digest until the end of each cycle
+func (c Config) IsBallotDigestCycle() bool {
+	return c.BallotDigestCycle
+}
+
+// GetBallotDigestThreshold returns the voting power in percent of the total above which ballots are also published on their own in digest mode
+func (c Config) GetBallotDigestThreshold() float64 {
+	return c.BallotDigestThreshold
+}
+
+// IsMonitorMilestone returns true if should publish quorum, supermajority and participation milestones
+func (c Config) IsMonitorMilestone() bool {
+	return c.MonitorMilestone
+}
+
+// GetParticipationMilestones returns the participations in percent announced once per ballot period
+func (c Config) GetParticipationMilestones() []float64 {
+	return c.ParticipationMilestones
+}
+
+// GetStateFile returns the file where the bot state is persisted across restarts
+func (c Config) GetStateFile() string {
+	return c.StateFile
+}
+
+// IsMonitorNonVoters returns true if should remind the bakers who did not vote yet during ballot periods
+func (c Config) IsMonitorNonVoters() bool {
+	return c.MonitorNonVoters
+}
+
+// IsNonVotersEveryCycle returns true if non voters are reported at the end of every cycle rather than only before the last one
+func (c Config) IsNonVotersEveryCycle() bool {
+	return c.NonVotersEveryCycle
+}
+
+// GetNonVotersTop returns the number of largest non voters listed in reports
+func (c Config) GetNonVotersTop() int {
+	return c.NonVotersTop
+}
+
+// IsMonitorLeaderboard returns true if should publish a leaderboard of all the proposals at the end of each cycle of proposal periods
+func (c Config) IsMonitorLeaderboard() bool {
+	return c.MonitorLeaderboard
+}
+
+// GetSchedules returns the scheduled announcements
+func (c Config) GetSchedules() []Schedule {
+	return c.Schedules
+}
+
+// IsMonitorHead returns true if every block should be emitted with its voting period, scheduled announcements rely on
+// them and are not posted in history mode
+func (c Config) IsMonitorHead() bool {
+	return len(c.Schedules) != 0 && !c.IsHistory()
+}
+
+// GetEventStore returns the database file the detected events are recorded in, empty disables recording
+func (c Config) GetEventStore() string {
+	return c.EventStore
+}
+
+// GetTwitterMentions returns the twitter handles of the bakers who opted in to be mentioned, by address
+func (c Config) GetTwitterMentions() map[string]string {
+	mentions := make(map[string]string)
+	for pkh, m := range c.Mentions {
+		if m.Twitter != "" {
+			mentions[pkh] = m.Twitter
+		}
+	}
+	return mentions
+}
+
+// GetActivationCountdown returns how long before a protocol activation countdown messages are posted
+func (c Config) GetActivationCountdown() []time.Duration {
+	return c.ActivationCountdown
+}
+
+// GetSupermajority returns the share of yay votes in percent required to approve a proposal, 0 uses the protocol default
+func (c Config) GetSupermajority() float64 {
+	return c.Supermajority
+}
+
 // GetTwitterAccessToken returns the twitter access token
 func (c Config) GetTwitterAccessToken() string {
 	return c.TwitterAccessToken
@@ -82,6 +288,52 @@ func (c Config) GetTwitterConsummerKey() string {
 	return c.TwitterConsummerKey
 }
 
+// GetHealthAddr returns the address the health endpoints listen on
+func (c Config) GetHealthAddr() string {
+	return c.HealthAddr
+}
+
+// GetAPIAddr returns the address the governance api listens on, empty disables it
+func (c Config) GetAPIAddr() string {
+	return c.APIAddr
+}
+
+// GetStreamBuffer returns how many recent events are kept for live stream clients to resume from when the api is enabled, 0 disables the stream
+func (c Config) GetStreamBuffer() int {
+	return c.StreamBuffer
+}
+
+// GetReadinessTimeout returns how long the bot can go without a new block before it is reported as not ready
+func (c Config) GetReadinessTimeout() time.Duration {
+	return c.ReadinessTimeout
+}
+
+// GetStreamFile returns the file the stream buffer is appended to so clients can resume across restarts, empty
+// only keeps it in memory
+func (c Config) GetStreamFile() string {
+	return c.StreamFile
+}
+
+// GetAdminAddr returns the address the admin web ui listens on, empty disables it
+func (c Config) GetAdminAddr() string {
+	return c.AdminAddr
+}
+
+// GetAdminToken returns the token granting access to the admin web ui
+func (c Config) GetAdminToken() string {
+	return c.AdminToken
+}
+
+// GetAdminUser returns the basic auth user granted access to the admin web ui
+func (c Config) GetAdminUser() string {
+	return c.AdminUser
+}
+
+// GetAdminPassword returns the basic auth password of the admin user
+func (c Config) GetAdminPassword() string {
+	return c.AdminPassword
+}
+
 // Load read a config file and unmarshal it into the config struct
 func (c *Config) Load(name string) error {
 	buf, err := ioutil.ReadFile(name)
@@ -95,3 +347,93 @@ func (c *Config) Load(name string) error {
 
 	return nil
 }
+
+// LoadStrict read configuration from a yaml file like Load but fails on unknown keys
+func (c *Config) LoadStrict(name string) error {
+	buf, err := ioutil.ReadFile(name)
+	if err != nil {
+		return err
+	}
+
+	return yaml.UnmarshalStrict(buf, c)
+}
+
+// Validate checks that the configuration is consistent and returns every problem found
+func (c Config) Validate() []error {
+	errs := []error{}
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	urls := c.GetRPCURLs()
+	check(len(urls) != 0, "no rpc node configured, set rpc_urls")
+	for _, u := range urls {
+		parsed, err := url.Parse(u)
+		check(err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != "", "rpc url %q is not a valid http(s) url", u)
+	}
+	check(c.ChainID != "", "chain must not be empty")
+	check(c.MaxHeadLag >= 0, "max_head_lag must not be negative")
+	check(c.RetryCount >= 0, "retry_count must not be negative")
+
+	check(c.HistoryStartingBlock >= 0, "history_starting_block must not be negative")
+	check(c.HistoryEndingBlock == 0 || c.HistoryEndingBlock >= c.HistoryStartingBlock, "history_ending_block %d is before history_starting_block %d", c.HistoryEndingBlock, c.HistoryStartingBlock)
+	check(c.HistoryWorkers >= 0, "history_workers must not be negative")
+	check(c.HistoryRate >= 0, "history_rate must not be negative")
+
+	check(c.Supermajority >= 0 && c.Supermajority <= 100, "supermajority %v is not a percentage", c.Supermajority)
+	check(c.BallotDigestThreshold >= 0 && c.BallotDigestThreshold <= 100, "ballot_digest_threshold %v is not a percentage", c.BallotDigestThreshold)
+	for _, m := range c.ParticipationMilestones {
+		check(m > 0 && m <= 100, "participation milestone %v is not a percentage", m)
+	}
+	for _, d := range c.ActivationCountdown {
+		check(d > 0, "activation countdown %s must be positive", d)
+	}
+	check(c.NonVotersTop >= 0, "non_voters_top must not be negative")
+	check(c.StreamBuffer >= 0, "stream_buffer must not be negative")
+
+	check((c.AdminUser == "") == (c.AdminPassword == ""), "admin_user and admin_password must be set together")
+	check(c.AdminAddr == "" || c.AdminToken != "" || c.AdminUser != "", "admin_addr requires admin_token or admin_user and admin_password")
+
+	twitter := []string{c.TwitterAccessToken, c.TwitterAccessTokenSecret, c.TwitterConsummerID, c.TwitterConsummerKey}
+	set := 0
+	for _, v := range twitter {
+		if v != "" {
+			set++
+		}
+	}
+	check(set == 0 || set == len(twitter), "twitter credentials are incomplete, the access token, its secret and the consummer id and key are all required")
+
+	names := make(map[string]bool)
+	for i, sc := range c.Schedules {
+		check(sc.Name != "", "schedule %d has no name", i+1)
+		check(!names[sc.Name], "schedule name %q is used more than once", sc.Name)
+		names[sc.Name] = true
+		check(sc.Template != "", "schedule %q has no template", sc.Name)
+		triggers := 0
+		for _, set := range []bool{sc.BlocksBeforePeriodEnd != 0, sc.Activation, sc.Level != 0, sc.Cron != ""} {
+			if set {
+				triggers++
+			}
+		}
+		check(triggers == 1, "schedule %q must have exactly one of blocks_before_period_end, activation, level or cron", sc.Name)
+		check(sc.BlocksBeforePeriodEnd >= 0, "schedule %q blocks_before_period_end must not be negative", sc.Name)
+		check(sc.Level >= 0, "schedule %q level must not be negative", sc.Name)
+		check(len(sc.PeriodKinds) == 0 || sc.BlocksBeforePeriodEnd != 0, "schedule %q period_kinds requires blocks_before_period_end", sc.Name)
+		for _, kind := range sc.PeriodKinds {
+			switch kind {
+			case "proposal", "exploration", "cooldown", "promotion", "adoption":
+			default:
+				check(false, "schedule %q period kind %q is not one of proposal, exploration, cooldown, promotion or adoption", sc.Name, kind)
+			}
+		}
+	}
+
+	for address, m := range c.Mentions {
+		check(strings.HasPrefix(address, "tz") || strings.HasPrefix(address, "KT1"), "mention address %q is not a tezos address", address)
+		check(m.Twitter != "", "mention of %s has no handle", address)
+	}
+
+	return errs
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/ecadlabs/tezos-bot/export"
+	"github.com/ecadlabs/tezos-bot/health"
+	"github.com/ecadlabs/tezos-bot/listen"
+	"github.com/ecadlabs/tezos-bot/models"
+)
+
+// runExport runs the export subcommand and returns the exit code
+func runExport(args []string) int {
+	fs := newFlagSet("export", "ballots|upvotes|outcomes [flags]", "Export the ballots, proposal upvotes or period outcomes of a voting period or a range of levels,\nread directly from the node.")
+	configFile := configFlag(fs)
+
+	var format, output string
+	var period, from, to int
+	fs.IntVar(&period, "period", -1, "Voting period index to export.")
+	fs.IntVar(&from, "from", 0, "First level to export, instead of a period.")
+	fs.IntVar(&to, "to", 0, "Last level to export, defaults to the current head.")
+	fs.StringVar(&format, "format", export.FormatCSV, "Output format, csv or jsonl.")
+	fs.StringVar(&output, "o", "", "Output file, defaults to stdout.")
+
+	if len(args) == 0 {
+		fs.Usage()
+		return 2
+	}
+	kind := args[0]
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	var columns []string
+	switch kind {
+	case "ballots":
+		columns = export.BallotColumns
+	case "upvotes":
+		columns = export.UpvoteColumns
+	case "outcomes":
+		columns = export.OutcomeColumns
+	default:
+		fs.Usage()
+		return 2
+	}
+
+	if format != export.FormatCSV && format != export.FormatJSONL {
+		fmt.Fprintf(os.Stderr, "export: unknown format %q, expected %s or %s\n", format, export.FormatCSV, export.FormatJSONL)
+		return 2
+	}
+
+	if period < 0 && from <= 0 {
+		fmt.Fprintln(os.Stderr, "export: either -period or -from is required")
+		return 2
+	}
+
+	c, err := loadConfig(*configFile)
+	if err != nil {
+		log.Printf(err.Error())
+		return 1
+	}
+
+	var out io.Writer = os.Stdout
+	if output != "" {
+		f, err := os.Create(output)
+		if err != nil {
+			log.Printf(err.Error())
+			return 1
+		}
+		defer f.Close()
+		out = f
+	}
+
+	w, err := export.NewWriter(out, format, columns)
+	if err != nil {
+		log.Printf(err.Error())
+		return 1
+	}
+
+	exporter, err := listen.NewExporter(c, health.NewStatus())
+	if err != nil {
+		log.Printf(err.Error())
+		return 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sig
+		cancel()
+	}()
+
+	if period >= 0 {
+		if from, to, err = exporter.PeriodLevels(ctx, period); err != nil {
+			log.Printf("Unable to find voting period %d: %s\n", period, err.Error())
+			return 1
+		}
+	} else if to <= 0 {
+		if to, err = exporter.Head(ctx); err != nil {
+			log.Printf(err.Error())
+			return 1
+		}
+	}
+
+	log.Printf("Exporting %s of levels %d to %d\n", kind, from, to)
+
+	count := 0
+	write := func(r export.Record) error {
+		count++
+		return w.Write(r)
+	}
+
+	switch kind {
+	case "ballots":
+		err = exporter.Ballots(ctx, from, to, func(b *models.Ballot) error {
+			return write(export.NewBallotRecord(b))
+		})
+	case "upvotes":
+		err = exporter.Upvotes(ctx, from, to, func(p *models.Proposal) error {
+			return write(export.NewUpvoteRecord(p))
+		})
+	case "outcomes":
+		err = exporter.Outcomes(ctx, from, to, func(t *models.PeriodTransition) error {
+			return write(export.NewOutcomeRecord(t))
+		})
+	}
+	if ferr := w.Flush(); err == nil {
+		err = ferr
+	}
+	if err == nil && ctx.Err() != nil {
+		err = ctx.Err()
+	}
+
+	if err != nil {
+		log.Printf("Export failed after %d records: %s\n", count, err.Error())
+		return 1
+	}
+
+	log.Printf("Exported %d %s\n", count, kind)
+	return 0
+}
@@ -0,0 +1,214 @@
+package export
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/ecadlabs/tezos-bot/models"
+)
+
+// BallotColumns are the columns of a ballot export
+var BallotColumns = []string{"level", "timestamp", "operation_hash", "period", "phase", "pkh", "ballot", "proposal_hash", "voting_power", "unit"}
+
+// BallotRecord is a ballot cast by a baker
+type BallotRecord struct {
+	Level         int       `json:"level"`
+	Timestamp     time.Time `json:"timestamp"`
+	OperationHash string    `json:"operation_hash"`
+	Period        int       `json:"period"`
+	Phase         string    `json:"phase"`
+	PKH           string    `json:"pkh"`
+	Ballot        string    `json:"ballot"`
+	ProposalHash  string    `json:"proposal_hash"`
+	VotingPower   int64     `json:"voting_power"`
+	Unit          string    `json:"unit"`
+}
+
+// NewBallotRecord create a new BallotRecord
+func NewBallotRecord(b *models.Ballot) *BallotRecord {
+	return &BallotRecord{
+		Level:         b.Level,
+		Timestamp:     b.Timestamp,
+		OperationHash: b.OperationHash,
+		Period:        b.Period,
+		Phase:         b.Phase(),
+		PKH:           b.PKH,
+		Ballot:        b.Ballot,
+		ProposalHash:  b.ProposalHash,
+		VotingPower:   b.VotingPower,
+		Unit:          b.Unit.String(),
+	}
+}
+
+// Values returns the fields of the record in the order of BallotColumns
+func (r *BallotRecord) Values() []string {
+	return []string{
+		strconv.Itoa(r.Level),
+		formatTime(r.Timestamp),
+		r.OperationHash,
+		strconv.Itoa(r.Period),
+		r.Phase,
+		r.PKH,
+		r.Ballot,
+		r.ProposalHash,
+		strconv.FormatInt(r.VotingPower, 10),
+		r.Unit,
+	}
+}
+
+// UpvoteColumns are the columns of a proposal upvote export
+var UpvoteColumns = []string{"level", "timestamp", "operation_hash", "period", "pkh", "proposal_hash", "voting_power", "total_voting_power", "unit"}
+
+// UpvoteRecord is a proposal injected or upvoted by a baker
+type UpvoteRecord struct {
+	Level            int       `json:"level"`
+	Timestamp        time.Time `json:"timestamp"`
+	OperationHash    string    `json:"operation_hash"`
+	Period           int       `json:"period"`
+	PKH              string    `json:"pkh"`
+	ProposalHash     string    `json:"proposal_hash"`
+	VotingPower      int64     `json:"voting_power"`
+	TotalVotingPower int64     `json:"total_voting_power"`
+	Unit             string    `json:"unit"`
+}
+
+// NewUpvoteRecord create a new UpvoteRecord
+func NewUpvoteRecord(p *models.Proposal) *UpvoteRecord {
+	return &UpvoteRecord{
+		Level:            p.Level,
+		Timestamp:        p.Timestamp,
+		OperationHash:    p.OperationHash,
+		Period:           p.Period,
+		PKH:              p.PKH,
+		ProposalHash:     p.ProposalHash,
+		VotingPower:      p.VotingPower,
+		TotalVotingPower: p.TotalVotingPower,
+		Unit:             p.Unit.String(),
+	}
+}
+
+// Values returns the fields of the record in the order of UpvoteColumns
+func (r *UpvoteRecord) Values() []string {
+	return []string{
+		strconv.Itoa(r.Level),
+		formatTime(r.Timestamp),
+		r.OperationHash,
+		strconv.Itoa(r.Period),
+		r.PKH,
+		r.ProposalHash,
+		strconv.FormatInt(r.VotingPower, 10),
+		strconv.FormatInt(r.TotalVotingPower, 10),
+		r.Unit,
+	}
+}
+
+// OutcomeColumns are the columns of a period outcome export
+var OutcomeColumns = []string{
+	"period", "kind", "next_period", "next_kind", "proposal_hash", "level", "timestamp",
+	"yay", "nay", "pass", "total_voting_power", "unit", "participation", "quorum", "supermajority", "percent_yay", "passed", "failure_reason",
+}
+
+// OutcomeRecord is the outcome of a voting period, the tallies are only set for ballot periods
+type OutcomeRecord struct {
+	Period           int       `json:"period"`
+	Kind             string    `json:"kind"`
+	NextPeriod       int       `json:"next_period"`
+	NextKind         string    `json:"next_kind"`
+	ProposalHash     string    `json:"proposal_hash,omitempty"`
+	Level            int       `json:"level"`
+	Timestamp        time.Time `json:"timestamp"`
+	Yay              *int64    `json:"yay,omitempty"`
+	Nay              *int64    `json:"nay,omitempty"`
+	Pass             *int64    `json:"pass,omitempty"`
+	TotalVotingPower *int64    `json:"total_voting_power,omitempty"`
+	Unit             string    `json:"unit,omitempty"`
+	Participation    *float64  `json:"participation,omitempty"`
+	Quorum           *float64  `json:"quorum,omitempty"`
+	Supermajority    *float64  `json:"supermajority,omitempty"`
+	PercentYay       *float64  `json:"percent_yay,omitempty"`
+	Passed           *bool     `json:"passed,omitempty"`
+	FailureReason    string    `json:"failure_reason,omitempty"`
+}
+
+// NewOutcomeRecord create a new OutcomeRecord
+func NewOutcomeRecord(t *models.PeriodTransition) *OutcomeRecord {
+	r := &OutcomeRecord{
+		Period:       t.FromIndex,
+		Kind:         t.FromKind,
+		NextPeriod:   t.ToIndex,
+		NextKind:     t.ToKind,
+		ProposalHash: t.ProposalHash,
+		Level:        t.Level,
+		Timestamp:    t.Timestamp,
+	}
+	if res := t.Result; res != nil {
+		participation := res.PercentParticipation()
+		quorum := res.Quorum.Percent()
+		percentYay := res.PercentYay()
+		passed := res.Passed()
+		r.Yay = &res.Yay
+		r.Nay = &res.Nay
+		r.Pass = &res.Pass
+		r.TotalVotingPower = &res.TotalVotingPower
+		r.Unit = res.Unit.String()
+		r.Participation = &participation
+		r.Quorum = &quorum
+		r.Supermajority = &res.Supermajority
+		r.PercentYay = &percentYay
+		r.Passed = &passed
+		r.FailureReason = res.FailureReason()
+	}
+	return r
+}
+
+// Values returns the fields of the record in the order of OutcomeColumns, tallies are empty for periods without ballots
+func (r *OutcomeRecord) Values() []string {
+	return []string{
+		strconv.Itoa(r.Period),
+		r.Kind,
+		strconv.Itoa(r.NextPeriod),
+		r.NextKind,
+		r.ProposalHash,
+		strconv.Itoa(r.Level),
+		formatTime(r.Timestamp),
+		formatInt(r.Yay),
+		formatInt(r.Nay),
+		formatInt(r.Pass),
+		formatInt(r.TotalVotingPower),
+		r.Unit,
+		formatFloat(r.Participation),
+		formatFloat(r.Quorum),
+		formatFloat(r.Supermajority),
+		formatFloat(r.PercentYay),
+		formatBool(r.Passed),
+		r.FailureReason,
+	}
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func formatInt(v *int64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatInt(*v, 10)
+}
+
+func formatFloat(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*v, 'f', 2, 64)
+}
+
+func formatBool(v *bool) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatBool(*v)
+}
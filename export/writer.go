@@ -0,0 +1,68 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Export formats
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+)
+
+// Record is a row of an export
+type Record interface {
+	// Values returns the fields of the record in the order of its columns
+	Values() []string
+}
+
+// Writer writes records as CSV, with a header line, or as JSON Lines
+type Writer struct {
+	columns []string
+	csv     *csv.Writer
+	json    *json.Encoder
+	started bool
+}
+
+// NewWriter create a new Writer, columns is the CSV header
+func NewWriter(w io.Writer, format string, columns []string) (*Writer, error) {
+	switch format {
+	case FormatCSV:
+		return &Writer{columns: columns, csv: csv.NewWriter(w)}, nil
+	case FormatJSONL:
+		return &Writer{columns: columns, json: json.NewEncoder(w)}, nil
+	}
+	return nil, fmt.Errorf("unknown export format %q, expected %s or %s", format, FormatCSV, FormatJSONL)
+}
+
+// Write writes a record
+func (w *Writer) Write(r Record) error {
+	if w.json != nil {
+		return w.json.Encode(r)
+	}
+	if !w.started {
+		w.started = true
+		if err := w.csv.Write(w.columns); err != nil {
+			return err
+		}
+	}
+	return w.csv.Write(r.Values())
+}
+
+// Flush writes any buffered data, the CSV header is written even without records
+func (w *Writer) Flush() error {
+	if w.csv == nil {
+		return nil
+	}
+	if !w.started {
+		w.started = true
+		if err := w.csv.Write(w.columns); err != nil {
+			return err
+		}
+	}
+	w.csv.Flush()
+	return w.csv.Error()
+}
@@ -0,0 +1,135 @@
+package health
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// HealthConfig interface with method necessary to obtain health server configurable parameter
+type HealthConfig interface {
+	GetHealthAddr() string
+	GetReadinessTimeout() time.Duration
+}
+
+// Server exposes the liveness and readiness endpoints
+type Server struct {
+	status *Status
+	config HealthConfig
+	server *http.Server
+}
+
+type healthResponse struct {
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+	Report
+}
+
+// NewServer create a new health Server
+func NewServer(config HealthConfig, status *Status) *Server {
+	s := &Server{
+		status: status,
+		config: config,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", s.handleHealthz)
+	mux.HandleFunc("/readyz", s.handleReadyz)
+	mux.HandleFunc("/metrics", s.handleMetrics)
+
+	s.server = &http.Server{
+		Addr:    config.GetHealthAddr(),
+		Handler: mux,
+	}
+
+	return s
+}
+
+// Start serves the health endpoints in the background
+func (s *Server) Start() {
+	go func() {
+		log.Printf("Health server listening on %s\n", s.server.Addr)
+		if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Printf("Health server stopped because of error: %s\n", err.Error())
+		}
+	}()
+}
+
+// Stop stop the health server
+func (s *Server) Stop() error {
+	return s.server.Close()
+}
+
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	writeHealthResponse(w, http.StatusOK, healthResponse{
+		Status: "ok",
+		Report: s.status.Report(),
+	})
+}
+
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	resp := healthResponse{
+		Status: "ok",
+		Report: s.status.Report(),
+	}
+	code := http.StatusOK
+
+	since, ok := s.status.SinceLastHead()
+	if !ok {
+		resp.Status = "unavailable"
+		resp.Reason = "no block received yet"
+		code = http.StatusServiceUnavailable
+	} else if resp.StreamState == StreamFailed {
+		resp.Status = "unavailable"
+		resp.Reason = "block stream gave up reconnecting"
+		code = http.StatusServiceUnavailable
+	} else if timeout := s.config.GetReadinessTimeout(); timeout > 0 && since > timeout {
+		resp.Status = "unavailable"
+		resp.Reason = fmt.Sprintf("no block received for %s", since.Round(time.Second))
+		code = http.StatusServiceUnavailable
+	}
+
+	writeHealthResponse(w, code, resp)
+}
+
+// handleMetrics exposes the status in the prometheus text format
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	report := s.status.Report()
+
+	streamUp := 0
+	if report.StreamState == StreamStreaming {
+		streamUp = 1
+	}
+
+	nodeUp := 0
+	if report.NodeReachable {
+		nodeUp = 1
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	fmt.Fprintf(w, "# HELP tezos_bot_stream_up Whether the block stream is currently receiving heads.\n")
+	fmt.Fprintf(w, "# TYPE tezos_bot_stream_up gauge\n")
+	fmt.Fprintf(w, "tezos_bot_stream_up %d\n", streamUp)
+	fmt.Fprintf(w, "# HELP tezos_bot_stream_reconnects_total Number of reconnections to the block stream.\n")
+	fmt.Fprintf(w, "# TYPE tezos_bot_stream_reconnects_total counter\n")
+	fmt.Fprintf(w, "tezos_bot_stream_reconnects_total %d\n", report.StreamReconnects)
+	fmt.Fprintf(w, "# HELP tezos_bot_node_up Whether the last request to the rpc node succeeded.\n")
+	fmt.Fprintf(w, "# TYPE tezos_bot_node_up gauge\n")
+	fmt.Fprintf(w, "tezos_bot_node_up %d\n", nodeUp)
+	fmt.Fprintf(w, "# HELP tezos_bot_last_head_level Level of the last block received.\n")
+	fmt.Fprintf(w, "# TYPE tezos_bot_last_head_level gauge\n")
+	fmt.Fprintf(w, "tezos_bot_last_head_level %d\n", report.LastHeadLevel)
+	fmt.Fprintf(w, "# HELP tezos_bot_seconds_since_last_head Seconds elapsed since the last block was received.\n")
+	fmt.Fprintf(w, "# TYPE tezos_bot_seconds_since_last_head gauge\n")
+	fmt.Fprintf(w, "tezos_bot_seconds_since_last_head %f\n", report.SecondsSinceLastHead)
+}
+
+func writeHealthResponse(w http.ResponseWriter, code int, resp healthResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Printf("Unable to write health response: %s\n", err.Error())
+	}
+}
@@ -0,0 +1,206 @@
+package health
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// ModeHistory is reported while the listener replays past blocks
+	ModeHistory = "history"
+	// ModeLive is reported while the listener follows new heads
+	ModeLive = "live"
+
+	// StreamConnecting is reported while connecting to the heads stream
+	StreamConnecting = "connecting"
+	// StreamStreaming is reported once heads are flowing
+	StreamStreaming = "streaming"
+	// StreamReconnecting is reported while waiting before the next reconnection attempt
+	StreamReconnecting = "reconnecting"
+	// StreamStopped is reported once the listener has been stopped
+	StreamStopped = "stopped"
+	// StreamFailed is reported once the retry limit has been reached
+	StreamFailed = "failed"
+)
+
+// Status holds the runtime state of the bot reported by the health endpoints
+type Status struct {
+	mu            sync.RWMutex
+	mode          string
+	lastHead      time.Time
+	lastLevel     int
+	lastHash      string
+	nodeReachable bool
+	nodeError     string
+	streamState   string
+	reconnects    int64
+	nodes         map[string]NodeReport
+	backfill      *BackfillReport
+	publishers    map[string]bool
+}
+
+// BackfillReport holds the progress of the history backfill
+type BackfillReport struct {
+	StartLevel   int     `json:"start_level"`
+	EndLevel     int     `json:"end_level"`
+	CurrentLevel int     `json:"current_level"`
+	Percent      float64 `json:"percent"`
+}
+
+// NodeReport holds the last known state of a tezos node
+type NodeReport struct {
+	Level     int    `json:"level"`
+	Active    bool   `json:"active"`
+	Reachable bool   `json:"reachable"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Report is a point in time snapshot of Status
+type Report struct {
+	Mode                 string                `json:"mode"`
+	LastHeadLevel        int                   `json:"last_head_level"`
+	LastHeadHash         string                `json:"last_head_hash"`
+	LastHeadTime         *time.Time            `json:"last_head_time,omitempty"`
+	SecondsSinceLastHead float64               `json:"seconds_since_last_head"`
+	NodeReachable        bool                  `json:"node_reachable"`
+	NodeError            string                `json:"node_error,omitempty"`
+	StreamState          string                `json:"stream_state"`
+	StreamReconnects     int64                 `json:"stream_reconnects"`
+	Nodes                map[string]NodeReport `json:"nodes"`
+	Backfill             *BackfillReport       `json:"backfill,omitempty"`
+	Publishers           map[string]bool       `json:"publishers"`
+}
+
+// NewStatus create a new Status
+func NewStatus() *Status {
+	return &Status{
+		nodes:      make(map[string]NodeReport),
+		publishers: make(map[string]bool),
+	}
+}
+
+// SetMode records whether the listener is in history or live mode
+func (s *Status) SetMode(mode string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mode = mode
+}
+
+// SetHead records the last block received by the listener
+func (s *Status) SetHead(level int, hash string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastHead = time.Now()
+	s.lastLevel = level
+	s.lastHash = hash
+	s.nodeReachable = true
+	s.nodeError = ""
+}
+
+// SetNodeError records the last error returned by the tezos node, nil marks the node as reachable
+func (s *Status) SetNodeError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nodeReachable = err == nil
+	s.nodeError = ""
+	if err != nil {
+		s.nodeError = err.Error()
+	}
+}
+
+// SetStreamState records the state of the block streaming subsystem
+func (s *Status) SetStreamState(state string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.streamState = state
+}
+
+// StreamReconnected increments the number of reconnections to the block stream
+func (s *Status) StreamReconnected() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reconnects++
+}
+
+// SetNode records the state of one of the tezos nodes
+func (s *Status) SetNode(url string, level int, active bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := NodeReport{
+		Level:     level,
+		Active:    active,
+		Reachable: err == nil,
+	}
+	if err != nil {
+		n.Error = err.Error()
+	}
+	s.nodes[url] = n
+}
+
+// SetBackfillProgress records the last level emitted by the history backfill
+func (s *Status) SetBackfillProgress(start, end, level int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.backfill = &BackfillReport{
+		StartLevel:   start,
+		EndLevel:     end,
+		CurrentLevel: level,
+		Percent:      float64(level-start+1) / float64(end-start+1) * 100,
+	}
+}
+
+// SetPublisher records whether the credentials of a publisher were verified at startup
+func (s *Status) SetPublisher(name string, verified bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.publishers[name] = verified
+}
+
+// Report returns a snapshot of the current status
+func (s *Status) Report() Report {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	r := Report{
+		Mode:             s.mode,
+		LastHeadLevel:    s.lastLevel,
+		LastHeadHash:     s.lastHash,
+		NodeReachable:    s.nodeReachable,
+		NodeError:        s.nodeError,
+		StreamState:      s.streamState,
+		StreamReconnects: s.reconnects,
+		Nodes:            make(map[string]NodeReport, len(s.nodes)),
+		Publishers:       make(map[string]bool, len(s.publishers)),
+	}
+
+	if !s.lastHead.IsZero() {
+		lastHead := s.lastHead
+		r.LastHeadTime = &lastHead
+		r.SecondsSinceLastHead = time.Since(lastHead).Seconds()
+	}
+
+	if s.backfill != nil {
+		backfill := *s.backfill
+		r.Backfill = &backfill
+	}
+
+	for url, n := range s.nodes {
+		r.Nodes[url] = n
+	}
+
+	for name, verified := range s.publishers {
+		r.Publishers[name] = verified
+	}
+
+	return r
+}
+
+// SinceLastHead returns the time elapsed since the last block was received, false if none was received yet
+func (s *Status) SinceLastHead() (time.Duration, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.lastHead.IsZero() {
+		return 0, false
+	}
+	return time.Since(s.lastHead), true
+}
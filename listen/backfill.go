@@ -0,0 +1,338 @@
+package listen
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"time"
+
+	tezos "github.com/ecadlabs/go-tezos"
+)
+
+const backfillProgressInterval = 10 * time.Second
+
+// backfillRange is a range of levels within a single voting period
+type backfillRange struct {
+	from   int
+	to     int
+	period *votingPeriod
+	// cycles locates the cycle ends of the period
+	cycles *cycleState
+	// relevant is true when an enabled monitor can produce events during the period
+	relevant bool
+}
+
+// isCheckpoint returns true for levels that are needed even without voting operations:
+// period boundaries for protocol changes and winning proposals, cycle ends for summaries
+func (r *backfillRange) isCheckpoint(level int) bool {
+	return level == r.period.StartLevel || level == r.period.EndLevel || r.cycles.isCycleEnd(level)
+}
+
+type backfillTask struct {
+	seq   int
+	level int
+	rng   *backfillRange
+}
+
+type backfillResult struct {
+	backfillTask
+	// block is nil when the level has nothing to process
+	block *tezos.Block
+	err   error
+}
+
+// rateLimiter spaces requests evenly to stay under a number of requests per second
+type rateLimiter struct {
+	ticker *time.Ticker
+}
+
+func newRateLimiter(rate float64) *rateLimiter {
+	if rate <= 0 {
+		return &rateLimiter{}
+	}
+	return &rateLimiter{
+		ticker: time.NewTicker(time.Duration(float64(time.Second) / rate)),
+	}
+}
+
+// Wait blocks until the next request is allowed or ctx is done
+func (r *rateLimiter) Wait(ctx context.Context) error {
+	if r.ticker == nil {
+		return ctx.Err()
+	}
+	select {
+	case <-r.ticker.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *rateLimiter) Stop() {
+	if r.ticker != nil {
+		r.ticker.Stop()
+	}
+}
+
+// isRelevantPeriod returns true if an enabled monitor can produce events during a period of the given kind
+func isRelevantPeriod(config TezosConfig, kind string) bool {
+	return ((config.IsMonitorProposal() || config.IsMonitorLeaderboard()) && isProposalPeriod(kind)) || ((config.IsMonitorVote() || config.IsMonitorMilestone()) && isBallotPeriod(kind))
+}
+
+// planBackfill splits the levels to replay by voting period
+func planBackfill(ctx context.Context, config TezosConfig, nodes *NodePool, start, end int) ([]*backfillRange, error) {
+	ranges := []*backfillRange{}
+	for level := start; level <= end; {
+		var period *votingPeriod
+		var c *constants
+		err := nodes.Do(ctx, func(service *tezos.Service) (err error) {
+			if period, err = getVotingPeriod(ctx, service, config.GetChainID(), level); err != nil {
+				return err
+			}
+			c, err = getConstants(ctx, service, config.GetChainID(), strconv.Itoa(level))
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		r := &backfillRange{
+			from:     level,
+			to:       period.EndLevel,
+			period:   period,
+			cycles:   &cycleState{period: period, blocksPerCycle: c.BlocksPerCycle},
+			relevant: isRelevantPeriod(config, period.Kind),
+		}
+		if r.to > end {
+			r.to = end
+		}
+		ranges = append(ranges, r)
+
+		if !r.relevant {
+			log.Printf("Backfill: skipping %s period %d (levels %d to %d), no monitor can produce events\n", period.Kind, period.Index, r.from, r.to)
+		}
+		level = period.EndLevel + 1
+	}
+	return ranges, nil
+}
+
+// wantLevel returns true if level has to be fetched
+func (r *backfillRange) wantLevel(config TezosConfig, level int) bool {
+	if r.relevant {
+		return true
+	}
+	if config.IsMonitorReport() && isBallotPeriod(r.period.Kind) && level == r.period.EndLevel {
+		return true
+	}
+	if config.IsMonitorNonVoters() && isBallotPeriod(r.period.Kind) && r.isCheckpoint(level) {
+		return true
+	}
+	return (config.IsMonitorProtocol() || config.IsMonitorProposal() || config.IsMonitorPeriod() || config.IsMonitorHead()) && (level == r.period.StartLevel || level == r.period.EndLevel)
+}
+
+// countBackfillTasks returns the number of levels to fetch
+func countBackfillTasks(config TezosConfig, ranges []*backfillRange) int {
+	count := 0
+	for _, r := range ranges {
+		for level := r.from; level <= r.to; level++ {
+			if r.wantLevel(config, level) {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// fetchBackfillBlock fetches the voting operations of a level, and its header only when there is something to process
+func fetchBackfillBlock(ctx context.Context, config TezosConfig, nodes *NodePool, task backfillTask) (*tezos.Block, error) {
+	var block *tezos.Block
+	err := nodes.Do(ctx, func(service *tezos.Service) error {
+		ops, err := getOperationsPass(ctx, service, config.GetChainID(), strconv.Itoa(task.level), votingValidationPass)
+		if err != nil {
+			return err
+		}
+		if len(ops) == 0 && !task.rng.isCheckpoint(task.level) {
+			return nil
+		}
+		block, err = getVotingBlock(ctx, service, config.GetChainID(), task.level, task.rng.period, ops)
+		return err
+	})
+	return block, err
+}
+
+// HistoryBlockStreamingFunc replays blocks from the history starting block to the history ending block
+// (or the current head). Voting periods in which no enabled monitor can produce events are skipped and
+// only the voting operations of each block are fetched. Blocks are fetched in parallel by a bounded pool
+// of rate limited workers but are emitted in strict level order
+func HistoryBlockStreamingFunc(ctx context.Context, config TezosConfig, nodes *NodePool, status StatusReporter, results chan<- *tezos.Block) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	start := config.GetHistoryStartingBlock()
+	end := config.GetHistoryEndingBlock()
+	if end <= 0 {
+		err := nodes.Do(ctx, func(service *tezos.Service) error {
+			header, err := getBlockHeader(ctx, service, config.GetChainID(), HEAD_BLOCK)
+			if err == nil {
+				end = header.Level
+			}
+			return err
+		})
+		if err != nil {
+			status.SetNodeError(err)
+			return err
+		}
+	}
+
+	if end < start {
+		log.Printf("Backfill: nothing to replay, ending level %d is before starting level %d\n", end, start)
+		return nil
+	}
+
+	ranges, err := planBackfill(ctx, config, nodes, start, end)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		status.SetNodeError(err)
+		return err
+	}
+	total := countBackfillTasks(config, ranges)
+
+	workers := config.GetHistoryWorkers()
+	if workers <= 0 {
+		workers = 1
+	}
+
+	limiter := newRateLimiter(config.GetHistoryRate())
+	defer limiter.Stop()
+
+	// window bounds how far the workers can get ahead of the next block to emit
+	window := make(chan struct{}, workers*4)
+	queue := make(chan backfillTask)
+	fetched := make(chan backfillResult)
+
+	go func() {
+		defer close(queue)
+		seq := 0
+		for _, r := range ranges {
+			for level := r.from; level <= r.to; level++ {
+				if !r.wantLevel(config, level) {
+					continue
+				}
+				select {
+				case window <- struct{}{}:
+				case <-ctx.Done():
+					return
+				}
+				select {
+				case queue <- backfillTask{seq: seq, level: level, rng: r}:
+				case <-ctx.Done():
+					return
+				}
+				seq++
+			}
+		}
+	}()
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			for task := range queue {
+				r := backfillResult{backfillTask: task}
+				if r.err = limiter.Wait(ctx); r.err == nil {
+					r.block, r.err = fetchBackfillBlock(ctx, config, nodes, task)
+				}
+				select {
+				case fetched <- r:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	log.Printf("Backfill: replaying levels %d to %d, fetching %d blocks with %d workers\n", start, end, total, workers)
+	progress := newBackfillProgress(start, end, status)
+	pending := make(map[int]backfillResult)
+	next := 0
+
+	for next < total {
+		var r backfillResult
+		select {
+		case r = <-fetched:
+		case <-ctx.Done():
+			return nil
+		}
+
+		if r.err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			status.SetNodeError(r.err)
+			return r.err
+		}
+
+		pending[r.seq] = r
+		for r, ok := pending[next]; ok; r, ok = pending[next] {
+			delete(pending, next)
+			if r.block != nil {
+				select {
+				case results <- r.block:
+				case <-ctx.Done():
+					return nil
+				}
+			}
+			<-window
+			progress.Done(r.level)
+			next++
+		}
+	}
+
+	progress.Finish()
+	return nil
+}
+
+// backfillProgress periodically logs and reports how far the backfill went
+type backfillProgress struct {
+	start      int
+	end        int
+	status     StatusReporter
+	started    time.Time
+	lastReport time.Time
+}
+
+func newBackfillProgress(start, end int, status StatusReporter) *backfillProgress {
+	now := time.Now()
+	status.SetBackfillProgress(start, end, start-1)
+	return &backfillProgress{
+		start:      start,
+		end:        end,
+		status:     status,
+		started:    now,
+		lastReport: now,
+	}
+}
+
+// Done records that level was processed
+func (p *backfillProgress) Done(level int) {
+	p.status.SetBackfillProgress(p.start, p.end, level)
+	if time.Since(p.lastReport) < backfillProgressInterval {
+		return
+	}
+	p.lastReport = time.Now()
+
+	done := level - p.start + 1
+	total := p.end - p.start + 1
+	rate := float64(done) / time.Since(p.started).Seconds()
+	eta := time.Duration(0)
+	if rate > 0 {
+		eta = time.Duration(float64(total-done)/rate) * time.Second
+	}
+	log.Printf("Backfill: level %d/%d (%.1f%%), %.1f levels/s, eta %s\n", level, p.end, float64(done)/float64(total)*100, rate, eta)
+}
+
+// Finish logs the end of the backfill and reports the end level as reached
+func (p *backfillProgress) Finish() {
+	p.status.SetBackfillProgress(p.start, p.end, p.end)
+	log.Printf("Backfill: replayed levels %d to %d in %s\n", p.start, p.end, time.Since(p.started).Round(time.Second))
+}
@@ -0,0 +1,129 @@
+package listen
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	tezos "github.com/ecadlabs/go-tezos"
+	"github.com/ecadlabs/tezos-bot/config"
+	"github.com/ecadlabs/tezos-bot/health"
+)
+
+// fakeChain serves a chain made of voting periods of 8 blocks and cycles of 4 blocks, going through
+// proposal, testing_vote, testing and promotion_vote. Lower levels are answered more slowly so that
+// the backfill workers complete out of order
+type fakeChain struct {
+	// ops lists the levels holding voting operations
+	ops map[int]bool
+}
+
+var fakePeriodKinds = []string{periodProposal, periodTestingVote, periodTesting, periodPromotionVote}
+
+func (c *fakeChain) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/chains/main/blocks/"), "/", 2)
+	level, err := strconv.Atoi(parts[0])
+	if err != nil || len(parts) != 2 {
+		http.NotFound(w, r)
+		return
+	}
+	time.Sleep(time.Duration(40-level) * time.Millisecond)
+
+	var res interface{}
+	switch parts[1] {
+	case "header":
+		res = map[string]interface{}{"level": level, "hash": "B" + strconv.Itoa(level), "protocol": "P"}
+	case "context/constants":
+		res = map[string]interface{}{"blocks_per_cycle": 4, "blocks_per_voting_period": 8}
+	case "votes/current_period":
+		index := (level - 1) / 8
+		position := (level - 1) % 8
+		res = map[string]interface{}{
+			"voting_period": map[string]interface{}{"index": index, "kind": fakePeriodKinds[index%4], "start_position": index * 8},
+			"position":      position,
+			"remaining":     7 - position,
+		}
+	case "operations/1":
+		ops := []*tezos.Operation{}
+		if c.ops[level] {
+			ops = append(ops, &tezos.Operation{Hash: "o" + strconv.Itoa(level)})
+		}
+		res = ops
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
+
+func TestHistoryBlockStreaming(t *testing.T) {
+	srv := httptest.NewServer(&fakeChain{ops: map[int]bool{6: true, 10: true, 14: true, 27: true}})
+	defer srv.Close()
+
+	// Levels 5 to 28 cover the end of a proposal period, a testing_vote period, a testing period and
+	// the start of a promotion_vote period
+	tests := []struct {
+		name string
+		conf config.Config
+		want []int
+	}{
+		{
+			name: "ballots",
+			conf: config.Config{MonitorVote: true},
+			// Ballot periods are fetched whole, blocks without ballots are only emitted at period and cycle ends
+			want: []int{9, 10, 12, 14, 16, 25, 27, 28},
+		},
+		{
+			name: "proposals",
+			conf: config.Config{MonitorProposal: true},
+			// Other periods are only fetched at their boundaries
+			want: []int{6, 8, 9, 16, 17, 24, 25},
+		},
+		{
+			name: "period boundaries",
+			conf: config.Config{MonitorProtocol: true},
+			want: []int{8, 9, 16, 17, 24, 25},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.conf.ChainID = "main"
+			tt.conf.History = true
+			tt.conf.HistoryStartingBlock = 5
+			tt.conf.HistoryEndingBlock = 28
+			tt.conf.HistoryWorkers = 4
+
+			status := health.NewStatus()
+			nodes, err := NewNodePool([]string{srv.URL}, 0, status)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			results := make(chan *tezos.Block, 32)
+			if err := HistoryBlockStreamingFunc(context.Background(), tt.conf, nodes, status, results); err != nil {
+				t.Fatal(err)
+			}
+			close(results)
+
+			got := []int{}
+			for block := range results {
+				level := block.Metadata.Level.Level
+				if want := (level - 1) / 8; block.Metadata.Level.VotingPeriod != want {
+					t.Errorf("block %d is in voting period %d, want %d", level, block.Metadata.Level.VotingPeriod, want)
+				}
+				got = append(got, level)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("HistoryBlockStreamingFunc() emitted %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package listen
+
+import (
+	"context"
+	"log"
+	"time"
+
+	tezos "github.com/ecadlabs/go-tezos"
+	"github.com/ecadlabs/tezos-bot/models"
+)
+
+const digestStateKey = "ballot_digest"
+
+// digestState holds the ballots of the current digest window. It is persisted so the ballots waiting for
+// the digest are not lost across restarts
+type digestState struct {
+	Start    time.Time `json:"start"`
+	EndLevel int       `json:"end_level"`
+	// CycleEnd is the last level of the cycle the window started in, only set when windows end with cycles
+	CycleEnd int                  `json:"cycle_end"`
+	Digest   *models.BallotDigest `json:"digest"`
+}
+
+// isBallotDigest returns true if ballots are aggregated into digests rather than published one by one
+func (t *TezosListener) isBallotDigest() bool {
+	return t.config.GetBallotDigestInterval() > 0 || t.config.IsBallotDigestCycle()
+}
+
+// publishBallot publishes a ballot on its own or adds it to the current digest window.
+// Ballots above the digest threshold are published on their own instead of being added to the digest
+func (t *TezosListener) publishBallot(ctx context.Context, block *tezos.Block, ballot *models.Ballot) error {
+	if !t.isBallotDigest() {
+		t.votesChan <- ballot
+		return nil
+	}
+
+	if threshold := t.config.GetBallotDigestThreshold(); threshold > 0 && ballot.PercentOfTotal() >= threshold {
+		t.votesChan <- ballot
+		return nil
+	}
+
+	d, err := t.getDigestState()
+	if err != nil {
+		return err
+	}
+	if d == nil {
+		if d, err = t.newDigestState(ctx, block, ballot); err != nil {
+			return err
+		}
+		t.digest = d
+	}
+
+	d.Digest.Ballots = append(d.Digest.Ballots, ballot)
+	return t.store.Save(digestStateKey, d)
+}
+
+// getDigestState returns the pending digest window, loading it from the store after a restart, nil if there is none
+func (t *TezosListener) getDigestState() (*digestState, error) {
+	if !t.digestLoaded {
+		if _, err := t.store.Load(digestStateKey, &t.digest); err != nil {
+			return nil, err
+		}
+		t.digestLoaded = true
+	}
+	return t.digest, nil
+}
+
+// newDigestState opens a digest window starting at block
+func (t *TezosListener) newDigestState(ctx context.Context, block *tezos.Block, ballot *models.Ballot) (*digestState, error) {
+	level := block.Header.Level
+	cycles, err := getCycleState(ctx, t.nodes.Service(), t.config.GetChainID(), level)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &digestState{
+		Start:    block.Header.Timestamp,
+		EndLevel: cycles.period.EndLevel,
+		Digest: &models.BallotDigest{
+			ProposalHash: ballot.ProposalHash,
+			Period:       cycles.period.Index,
+			IsTesting:    ballot.IsTesting,
+		},
+	}
+
+	if t.config.IsBallotDigestCycle() {
+		d.CycleEnd = cycles.cycleEnd(level)
+	}
+	return d, nil
+}
+
+// lookForBallotDigest emits the pending digest once block is past its window: the configured interval
+// elapsed, the cycle or the voting period ended
+func (t *TezosListener) lookForBallotDigest(block *tezos.Block) {
+	d, err := t.getDigestState()
+	if err != nil {
+		log.Printf("TezosListener: Unable to load the pending ballot digest: %s\n", err.Error())
+		return
+	}
+	if d == nil {
+		return
+	}
+
+	level := block.Header.Level
+	interval := t.config.GetBallotDigestInterval()
+	if level > d.EndLevel ||
+		(interval > 0 && block.Header.Timestamp.Sub(d.Start) >= interval) ||
+		(d.CycleEnd > 0 && level > d.CycleEnd) {
+		t.flushBallotDigest()
+	}
+}
+
+// flushBallotDigest emits the pending digest if any
+func (t *TezosListener) flushBallotDigest() {
+	d := t.digest
+	if d == nil {
+		return
+	}
+	log.Printf("TezosListener: Publishing digest of %d ballots.\n", len(d.Digest.Ballots))
+	// The digest is located at the block of its last ballot
+	ref := d.Digest.Latest().BlockRef
+	ref.OperationHash = ""
+	d.Digest.BlockRef = ref
+
+	// Save before publishing so a crash cannot publish the digest twice
+	t.digest = nil
+	if err := t.store.Save(digestStateKey, t.digest); err != nil {
+		log.Printf("TezosListener: Unable to save the ballot digest state: %s\n", err.Error())
+	}
+	t.ballotDigestChan <- d.Digest
+}
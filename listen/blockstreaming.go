@@ -2,65 +2,141 @@ package listen
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"time"
 
+	"github.com/cenkalti/backoff"
 	tezos "github.com/ecadlabs/go-tezos"
+	"github.com/ecadlabs/tezos-bot/health"
 )
 
 const (
 	HEAD_BLOCK = "head"
 )
 
-// BlockStreamingFunc function that emit a of block
-type BlockStreamingFunc func(ctx context.Context, config TezosConfig, service *tezos.Service, results chan<- string) error
+var (
+	errStreamClosed = errors.New("Heads stream closed by the rpc node")
+	errNodeSwitched = errors.New("Switched to another rpc node")
+)
 
-// MonitorBlockStreamingFunc emit the hash of each new head
-func MonitorBlockStreamingFunc(ctx context.Context, config TezosConfig, service *tezos.Service, results chan<- string) error {
-	cMonitorBlock := make(chan *tezos.MonitorBlock)
-	errCount := 0
-	defer close(cMonitorBlock)
-	go func() {
-		for block := range cMonitorBlock {
-			// Reset the error count on new block
-			errCount = 0
-			results <- block.Hash
-		}
-	}()
+// BlockStreamingFunc function that emit a stream of block
+type BlockStreamingFunc func(ctx context.Context, config TezosConfig, nodes *NodePool, status StatusReporter, results chan<- *tezos.Block) error
+
+// newStreamingBackOff returns the backoff policy used between reconnection attempts
+func newStreamingBackOff(config TezosConfig) backoff.BackOff {
+	b := backoff.NewExponentialBackOff()
+	b.MaxInterval = config.GetRetryMaxInterval()
+	// Never give up based on elapsed time, the retry count is the only limit
+	b.MaxElapsedTime = 0
+
+	if config.GetRetryCount() > 0 {
+		return backoff.WithMaxRetries(b, uint64(config.GetRetryCount()))
+	}
+	return b
+}
+
+// MonitorBlockStreamingFunc emit each new head, reconnecting with a capped exponential backoff when the stream fails
+func MonitorBlockStreamingFunc(ctx context.Context, config TezosConfig, nodes *NodePool, status StatusReporter, results chan<- *tezos.Block) error {
+	b := newStreamingBackOff(config)
+	attempt := 0
+	// The node sends the current head again on each reconnection
+	seen := newCache()
 
 	for {
-		err := service.GetMonitorHeads(ctx, config.GetChainID(), cMonitorBlock)
-		if err != nil {
-			// Retry connection until retry count is reached
-			if errCount > config.GetRetryCount() {
-				return fmt.Errorf("Unable to connect to rpc node after %d tries", config.GetRetryCount())
-			}
+		status.SetStreamState(health.StreamConnecting)
+		err := streamHeads(ctx, config, nodes, status, seen, results, func() {
+			// Reset the backoff on new block
+			attempt = 0
+			b.Reset()
+		})
+
+		if ctx.Err() != nil {
+			status.SetStreamState(health.StreamStopped)
+			return nil
+		}
+
+		if err == errNodeSwitched {
+			// The pool moved to a healthier node, reconnect right away
+			log.Printf("Streaming heads from %s\n", nodes.URL())
+			continue
+		}
+
+		if err == nil {
+			err = errStreamClosed
+		}
+
+		status.SetNodeError(err)
+		nodes.Failover(err)
+
+		wait := b.NextBackOff()
+		if wait == backoff.Stop {
+			status.SetStreamState(health.StreamFailed)
+			return fmt.Errorf("Unable to connect to rpc node after %d tries: %s", attempt, err.Error())
+		}
 
-			errCount++
-			log.Printf("Error encountered while trying to connect to rpc node (err count: %d): %s\n", errCount, err.Error())
-			time.Sleep(time.Duration(errCount) * time.Second)
+		attempt++
+		status.SetStreamState(health.StreamReconnecting)
+		status.StreamReconnected()
+		log.Printf("Error encountered while streaming heads from rpc node (attempt: %d, retrying in %s): %s\n", attempt, wait.Round(time.Millisecond), err.Error())
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			status.SetStreamState(health.StreamStopped)
+			return nil
 		}
 	}
 }
 
-// HistoryBlockStreamingFunc start from genesis and emit the hash of each block until current head
-func HistoryBlockStreamingFunc(ctx context.Context, config TezosConfig, service *tezos.Service, results chan<- string) error {
-	head, err := service.GetBlock(ctx, config.GetChainID(), HEAD_BLOCK)
-	if err != nil {
-		return err
+// streamHeads forwards heads from a single monitor connection until it ends, calling onHead for each head received
+func streamHeads(ctx context.Context, config TezosConfig, nodes *NodePool, status StatusReporter, seen *cache, results chan<- *tezos.Block, onHead func()) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Drop switch notifications that happened before this connection
+	select {
+	case <-nodes.Switched():
+	default:
 	}
 
-	level := head.Header.Level
-	i := config.GetHistoryStartingBlock()
-	for i <= level {
-		block, err := service.GetBlock(ctx, config.GetChainID(), fmt.Sprintf("%d", i))
-		if err != nil {
+	service := nodes.Service()
+	cMonitorBlock := make(chan *tezos.MonitorBlock)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- service.GetMonitorHeads(ctx, config.GetChainID(), cMonitorBlock)
+	}()
+
+	for {
+		select {
+		case head := <-cMonitorBlock:
+			onHead()
+			status.SetStreamState(health.StreamStreaming)
+			if seen.Has(head.Hash) {
+				continue
+			}
+			seen.Add(head.Hash)
+
+			block, err := service.GetBlock(ctx, config.GetChainID(), head.Hash)
+			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				status.SetNodeError(err)
+				log.Printf("Block: %s skipped because of error: %s\n", head.Hash, err.Error())
+				continue
+			}
+
+			select {
+			case results <- block:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		case err := <-errc:
 			return err
+		case <-nodes.Switched():
+			return errNodeSwitched
 		}
-		results <- block.Hash
-		time.Sleep(time.Second * 2)
-		i++
 	}
-	return nil
 }
@@ -0,0 +1,128 @@
+package listen
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	tezos "github.com/ecadlabs/go-tezos"
+	"github.com/ecadlabs/tezos-bot/config"
+	"github.com/ecadlabs/tezos-bot/health"
+)
+
+// fakeStream streams heads to the monitor clients then closes the stream, or holds it open until the client leaves
+type fakeStream struct {
+	heads []string
+	hold  bool
+	down  bool
+}
+
+func (s *fakeStream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.down {
+		http.Error(w, "node is down", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.URL.Path == "/monitor/heads/main" {
+		enc := json.NewEncoder(w)
+		for i, hash := range s.heads {
+			enc.Encode(map[string]interface{}{"hash": hash, "level": i + 1})
+			w.(http.Flusher).Flush()
+		}
+		if s.hold {
+			<-r.Context().Done()
+		}
+		return
+	}
+
+	hash := strings.TrimPrefix(r.URL.Path, "/chains/main/blocks/")
+	json.NewEncoder(w).Encode(map[string]interface{}{"hash": hash})
+}
+
+func TestMonitorBlockStreaming(t *testing.T) {
+	tests := []struct {
+		name       string
+		nodes      []*fakeStream
+		retryCount int
+		want       []string
+		wantErr    bool
+		wantState  string
+	}{
+		{
+			name: "reconnects to the next node",
+			// The head sent again on reconnection is only emitted once
+			nodes:     []*fakeStream{{heads: []string{"B1", "B2"}}, {heads: []string{"B2", "B3"}, hold: true}},
+			want:      []string{"B1", "B2", "B3"},
+			wantState: health.StreamStopped,
+		},
+		{
+			name:       "gives up after the retries",
+			nodes:      []*fakeStream{{down: true}, {down: true}},
+			retryCount: 2,
+			want:       []string{},
+			wantErr:    true,
+			wantState:  health.StreamFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			urls := []string{}
+			for _, n := range tt.nodes {
+				srv := httptest.NewServer(n)
+				defer srv.Close()
+				urls = append(urls, srv.URL)
+			}
+
+			status := health.NewStatus()
+			nodes, err := NewNodePool(urls, 0, status)
+			if err != nil {
+				t.Fatal(err)
+			}
+			conf := config.Config{ChainID: "main", RetryCount: tt.retryCount, RetryMaxInterval: 100 * time.Millisecond}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			results := make(chan *tezos.Block)
+			errc := make(chan error, 1)
+			go func() {
+				errc <- MonitorBlockStreamingFunc(ctx, conf, nodes, status, results)
+			}()
+
+			got := []string{}
+			timeout := time.After(10 * time.Second)
+			for done := false; !done; {
+				select {
+				case block := <-results:
+					got = append(got, block.Hash)
+					if len(got) == len(tt.want) {
+						cancel()
+					}
+				case err = <-errc:
+					done = true
+				case <-timeout:
+					t.Fatalf("MonitorBlockStreamingFunc() still running after receiving %v", got)
+				}
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MonitorBlockStreamingFunc() emitted %v, want %v", got, tt.want)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("MonitorBlockStreamingFunc() = %v, want error %v", err, tt.wantErr)
+			}
+			if state := status.Report().StreamState; state != tt.wantState {
+				t.Errorf("stream state = %q, want %q", state, tt.wantState)
+			}
+			if status.Report().StreamReconnects == 0 {
+				t.Errorf("no reconnection reported")
+			}
+		})
+	}
+}
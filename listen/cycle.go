@@ -0,0 +1,53 @@
+package listen
+
+import (
+	"context"
+	"strconv"
+
+	tezos "github.com/ecadlabs/go-tezos"
+)
+
+// cycleState is a voting period and the cycles it is made of
+type cycleState struct {
+	period         *votingPeriod
+	blocksPerCycle int
+}
+
+// getCycleState returns the voting period containing level and its cycle length
+func getCycleState(ctx context.Context, service *tezos.Service, chainID string, level int) (*cycleState, error) {
+	period, err := getVotingPeriod(ctx, service, chainID, level)
+	if err != nil {
+		return nil, err
+	}
+	c, err := getConstants(ctx, service, chainID, strconv.Itoa(level))
+	if err != nil {
+		return nil, err
+	}
+	return &cycleState{period: period, blocksPerCycle: c.BlocksPerCycle}, nil
+}
+
+// contains returns true if level is within the period
+func (s *cycleState) contains(level int) bool {
+	return level >= s.period.StartLevel && level <= s.period.EndLevel
+}
+
+// isCycleEnd returns true if level is the last block of a cycle, periods are made of whole cycles
+func (s *cycleState) isCycleEnd(level int) bool {
+	return s.blocksPerCycle > 0 && (level-s.period.StartLevel+1)%s.blocksPerCycle == 0
+}
+
+// cycle returns the position of the cycle containing level within the period, starting at 0
+func (s *cycleState) cycle(level int) int {
+	if s.blocksPerCycle <= 0 {
+		return 0
+	}
+	return (level - s.period.StartLevel) / s.blocksPerCycle
+}
+
+// cycleEnd returns the last level of the cycle containing level, the end of the period when cycles are unknown
+func (s *cycleState) cycleEnd(level int) int {
+	if s.blocksPerCycle <= 0 {
+		return s.period.EndLevel
+	}
+	return s.period.StartLevel + (s.cycle(level)+1)*s.blocksPerCycle - 1
+}
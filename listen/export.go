@@ -0,0 +1,293 @@
+package listen
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	tezos "github.com/ecadlabs/go-tezos"
+	"github.com/ecadlabs/tezos-bot/models"
+)
+
+// exportConfig replays a range of levels with only the monitors needed by an export enabled
+type exportConfig struct {
+	TezosConfig
+	from    int
+	to      int
+	ballots bool
+	upvotes bool
+}
+
+func (c exportConfig) IsHistory() bool {
+	return true
+}
+
+func (c exportConfig) GetHistoryStartingBlock() int {
+	return c.from
+}
+
+func (c exportConfig) GetHistoryEndingBlock() int {
+	return c.to
+}
+
+func (c exportConfig) IsMonitorVote() bool {
+	return c.ballots
+}
+
+func (c exportConfig) IsMonitorProposal() bool {
+	return c.upvotes
+}
+
+func (c exportConfig) IsMonitorProtocol() bool {
+	return false
+}
+
+func (c exportConfig) IsMonitorPeriod() bool {
+	return false
+}
+
+func (c exportConfig) IsMonitorReport() bool {
+	return false
+}
+
+func (c exportConfig) IsMonitorMilestone() bool {
+	return false
+}
+
+func (c exportConfig) IsMonitorNonVoters() bool {
+	return false
+}
+
+func (c exportConfig) IsMonitorLeaderboard() bool {
+	return false
+}
+
+func (c exportConfig) IsMonitorHead() bool {
+	return false
+}
+
+// Exporter reads the ballots, proposal upvotes and period outcomes of a range of levels directly from a node,
+// through the same rpc calls the listener makes
+type Exporter struct {
+	listener *TezosListener
+	status   StatusReporter
+	// listings of the last voting period seen, they do not change during a period
+	listingsPeriod int
+	listings       listings
+}
+
+// NewExporter create a new Exporter
+func NewExporter(config TezosConfig, status StatusReporter) (*Exporter, error) {
+	l, err := NewTezosListener(config, status, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &Exporter{listener: l, status: status, listingsPeriod: -1}, nil
+}
+
+// Head returns the level of the current head
+func (e *Exporter) Head(ctx context.Context) (int, error) {
+	return e.Level(ctx, HEAD_BLOCK)
+}
+
+// Level returns the level of a block given its hash, level or an alias such as head
+func (e *Exporter) Level(ctx context.Context, blockID string) (int, error) {
+	header, err := getBlockHeader(ctx, e.listener.nodes.Service(), e.listener.config.GetChainID(), blockID)
+	if err != nil {
+		return 0, err
+	}
+	return header.Level, nil
+}
+
+// PeriodLevels returns the first and last levels of the voting period with the given index, the last level
+// is the current head when the period is in progress
+func (e *Exporter) PeriodLevels(ctx context.Context, index int) (int, int, error) {
+	head, err := e.Head(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// Voting period indexes grow with levels
+	lo, hi := 1, head
+	for lo <= hi {
+		level := lo + (hi-lo)/2
+		period, err := getVotingPeriod(ctx, e.listener.nodes.Service(), e.listener.config.GetChainID(), level)
+		if err != nil {
+			return 0, 0, err
+		}
+		switch {
+		case period.Index < index:
+			lo = period.EndLevel + 1
+		case period.Index > index:
+			hi = period.StartLevel - 1
+		default:
+			end := period.EndLevel
+			if end > head {
+				end = head
+			}
+			return period.StartLevel, end, nil
+		}
+	}
+	return 0, 0, fmt.Errorf("voting period %d not found before level %d", index, head)
+}
+
+// Ballots calls fn with each ballot cast between from and to, in order
+func (e *Exporter) Ballots(ctx context.Context, from, to int, fn func(ballot *models.Ballot) error) error {
+	return e.scan(ctx, exportConfig{TezosConfig: e.listener.config, from: from, to: to, ballots: true}, func(block *tezos.Block) error {
+		for _, group := range block.Operations {
+			for _, op := range group {
+				for _, ballotOp := range tezos.FilterBallotOps(op.Contents) {
+					l, err := e.getListings(ctx, block)
+					if err != nil {
+						return err
+					}
+					err = fn(&models.Ballot{
+						BlockRef:         blockRef(block, op.Hash),
+						PKH:              ballotOp.Source,
+						Ballot:           ballotOp.Ballot,
+						ProposalHash:     ballotOp.Proposal,
+						VotingPower:      l.PowerOf(ballotOp.Source),
+						Unit:             l.Unit(),
+						IsTesting:        isExplorationPeriod(block.Metadata.VotingPeriodKind),
+						TotalVotingPower: float64(l.Total()),
+						Period:           block.Metadata.Level.VotingPeriod,
+					})
+					if err != nil {
+						return err
+					}
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// Upvotes calls fn with each proposal injection or upvote between from and to, in order
+func (e *Exporter) Upvotes(ctx context.Context, from, to int, fn func(proposal *models.Proposal) error) error {
+	return e.scan(ctx, exportConfig{TezosConfig: e.listener.config, from: from, to: to, upvotes: true}, func(block *tezos.Block) error {
+		for _, group := range block.Operations {
+			for _, op := range group {
+				for _, proposalOp := range tezos.FilterProposalOps(op.Contents) {
+					l, err := e.getListings(ctx, block)
+					if err != nil {
+						return err
+					}
+					for _, proposal := range proposalOp.Proposals {
+						err := fn(&models.Proposal{
+							BlockRef:         blockRef(block, op.Hash),
+							ProposalHash:     proposal,
+							PKH:              proposalOp.Source,
+							Period:           proposalOp.Period,
+							VotingPower:      l.PowerOf(proposalOp.Source),
+							TotalVotingPower: l.Total(),
+							Unit:             l.Unit(),
+						})
+						if err != nil {
+							return err
+						}
+					}
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// Outcomes calls fn with the transition of each voting period ending between from and to, in order
+func (e *Exporter) Outcomes(ctx context.Context, from, to int, fn func(transition *models.PeriodTransition) error) error {
+	head, err := e.Head(ctx)
+	if err != nil {
+		return err
+	}
+
+	service := e.listener.nodes.Service()
+	chainID := e.listener.config.GetChainID()
+	for level := from; level <= to; {
+		period, err := getVotingPeriod(ctx, service, chainID, level)
+		if err != nil {
+			return err
+		}
+		if period.EndLevel > to || period.EndLevel >= head {
+			return nil
+		}
+
+		next, err := getVotingPeriod(ctx, service, chainID, period.EndLevel+1)
+		if err != nil {
+			return err
+		}
+		header, err := getBlockHeader(ctx, service, chainID, strconv.Itoa(next.StartLevel))
+		if err != nil {
+			return err
+		}
+
+		transition := &models.PeriodTransition{
+			BlockRef: models.BlockRef{
+				Level:     next.StartLevel,
+				BlockHash: header.Hash,
+				Timestamp: header.Timestamp,
+			},
+			FromIndex: period.Index,
+			FromKind:  normalizePeriodKind(period.Kind),
+			ToIndex:   next.Index,
+			ToKind:    normalizePeriodKind(next.Kind),
+		}
+
+		// The winning proposal of a proposal period is the one under evaluation in the next period
+		proposalLevel := period.EndLevel
+		if isProposalPeriod(period.Kind) {
+			proposalLevel = next.StartLevel
+		}
+		if !isProposalPeriod(period.Kind) || !isProposalPeriod(next.Kind) {
+			if transition.ProposalHash, err = service.GetCurrentProposals(ctx, chainID, strconv.Itoa(proposalLevel)); err != nil {
+				return err
+			}
+		}
+
+		if isBallotPeriod(period.Kind) {
+			if transition.Result, err = e.listener.getBallotResult(ctx, period.EndLevel); err != nil {
+				return err
+			}
+		}
+
+		if err := fn(transition); err != nil {
+			return err
+		}
+		level = next.StartLevel
+	}
+	return nil
+}
+
+// scan replays the blocks holding voting operations between the levels of config
+func (e *Exporter) scan(ctx context.Context, config exportConfig, fn func(block *tezos.Block) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	blocks := make(chan *tezos.Block)
+	done := make(chan error, 1)
+	go func() {
+		done <- HistoryBlockStreamingFunc(ctx, config, e.listener.nodes, e.status, blocks)
+		close(blocks)
+	}()
+
+	for block := range blocks {
+		if err := fn(block); err != nil {
+			cancel()
+			for range blocks {
+			}
+			return err
+		}
+	}
+	return <-done
+}
+
+func (e *Exporter) getListings(ctx context.Context, block *tezos.Block) (listings, error) {
+	if period := block.Metadata.Level.VotingPeriod; period != e.listingsPeriod {
+		l, err := getListings(ctx, e.listener.nodes.Service(), e.listener.config.GetChainID(), block.Hash)
+		if err != nil {
+			return nil, err
+		}
+		e.listings = l
+		e.listingsPeriod = period
+	}
+	return e.listings, nil
+}
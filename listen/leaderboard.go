@@ -0,0 +1,161 @@
+package listen
+
+import (
+	"context"
+	"log"
+	"sort"
+
+	tezos "github.com/ecadlabs/go-tezos"
+	"github.com/ecadlabs/tezos-bot/models"
+)
+
+const leaderboardStateKey = "leaderboard"
+
+// leaderboardState tracks the supporters of each proposal during a proposal period, the proposals RPC only
+// returns their voting power. It is persisted so supporters seen before a restart are not lost
+type leaderboardState struct {
+	Period     int `json:"period"`
+	StartLevel int `json:"start_level"`
+	EndLevel   int `json:"end_level"`
+	// Complete is true when the listener saw every block of the period
+	Complete   bool                       `json:"complete"`
+	LastLevel  int                        `json:"last_level"`
+	Supporters map[string]map[string]bool `json:"supporters"`
+	// Reported is the voting power of each proposal in the previous leaderboard
+	Reported map[string]int64 `json:"reported"`
+
+	cycles *cycleState
+}
+
+// lookForProposalLeaderboard records the supporters of each proposal and emits a leaderboard of all the
+// proposals of the period at the end of each cycle
+func (t *TezosListener) lookForProposalLeaderboard(ctx context.Context, block *tezos.Block) error {
+	level := block.Header.Level
+	s, err := t.getLeaderboardState(ctx, level)
+	if err != nil {
+		return err
+	}
+
+	changed := false
+	for _, group := range block.Operations {
+		for _, op := range group {
+			for _, proposalOp := range tezos.FilterProposalOps(op.Contents) {
+				for _, proposal := range proposalOp.Proposals {
+					if s.Supporters[proposal] == nil {
+						s.Supporters[proposal] = make(map[string]bool)
+					}
+					s.Supporters[proposal][proposalOp.Source] = true
+					changed = true
+				}
+			}
+		}
+	}
+
+	// Blocks without voting operations are not fetched during a backfill
+	if s.LastLevel != 0 && level > s.LastLevel+1 && !t.config.IsHistory() {
+		s.Complete = false
+	}
+	s.LastLevel = level
+
+	if !s.cycles.isCycleEnd(level) {
+		if changed {
+			return t.store.Save(leaderboardStateKey, s)
+		}
+		return nil
+	}
+
+	log.Printf("TezosListener: Inspecting block %s for proposal leaderboard.\n", block.Hash)
+
+	proposals, err := getProposals(ctx, t.nodes.Service(), t.config.GetChainID(), block.Hash)
+	if err != nil {
+		return err
+	}
+
+	listings, err := getListings(ctx, t.nodes.Service(), t.config.GetChainID(), block.Hash)
+	if err != nil {
+		return err
+	}
+
+	c, err := getConstants(ctx, t.nodes.Service(), t.config.GetChainID(), block.Hash)
+	if err != nil {
+		return err
+	}
+
+	leaderboard := &models.ProposalLeaderboard{
+		BlockRef:           blockRef(block, ""),
+		Period:             s.Period,
+		Cycle:              s.cycles.cycle(level),
+		TotalVotingPower:   listings.Total(),
+		Unit:               listings.Unit(),
+		MinProposalQuorum:  float64(c.MinProposalQuorum) / 100,
+		SupportersComplete: s.Complete,
+	}
+
+	sort.SliceStable(proposals, func(i, j int) bool {
+		return proposals[i].Power > proposals[j].Power
+	})
+
+	for _, p := range proposals {
+		entry := models.LeaderboardEntry{
+			ProposalHash: p.ProposalHash,
+			VotingPower:  p.Power,
+			Delta:        p.Power - s.Reported[p.ProposalHash],
+			Supporters:   len(s.Supporters[p.ProposalHash]),
+		}
+		entry.AboveQuorum = leaderboard.PercentOfTotal(entry) >= leaderboard.MinProposalQuorum
+		leaderboard.Entries = append(leaderboard.Entries, entry)
+		s.Reported[p.ProposalHash] = p.Power
+	}
+
+	if err := t.store.Save(leaderboardStateKey, s); err != nil {
+		return err
+	}
+
+	if len(leaderboard.Entries) == 0 {
+		return nil
+	}
+
+	t.leaderboardChan <- leaderboard
+	return nil
+}
+
+// getLeaderboardState returns the leaderboard state of the proposal period containing level, loading it from
+// the store after a restart and resetting it when a new period begins
+func (t *TezosListener) getLeaderboardState(ctx context.Context, level int) (*leaderboardState, error) {
+	if t.leaderboard == nil {
+		var s leaderboardState
+		found, err := t.store.Load(leaderboardStateKey, &s)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			t.leaderboard = &s
+		}
+	}
+
+	s := t.leaderboard
+	if s == nil || s.cycles == nil || level < s.StartLevel || level > s.EndLevel {
+		cycles, err := getCycleState(ctx, t.nodes.Service(), t.config.GetChainID(), level)
+		if err != nil {
+			return nil, err
+		}
+		if s == nil || level < s.StartLevel || level > s.EndLevel {
+			s = &leaderboardState{
+				Period:     cycles.period.Index,
+				StartLevel: cycles.period.StartLevel,
+				EndLevel:   cycles.period.EndLevel,
+				Complete:   level == cycles.period.StartLevel,
+			}
+		}
+		s.cycles = cycles
+		t.leaderboard = s
+	}
+
+	if s.Supporters == nil {
+		s.Supporters = make(map[string]map[string]bool)
+	}
+	if s.Reported == nil {
+		s.Reported = make(map[string]int64)
+	}
+	return s, nil
+}
@@ -0,0 +1,148 @@
+package listen
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+
+	tezos "github.com/ecadlabs/go-tezos"
+	"github.com/ecadlabs/tezos-bot/models"
+)
+
+const milestoneStateKey = "milestones"
+
+// milestoneState is the ballot period being watched for milestones and the milestones it already reached.
+// It is persisted so milestones fire once per period across restarts
+type milestoneState struct {
+	Period     int             `json:"period"`
+	StartLevel int             `json:"start_level"`
+	EndLevel   int             `json:"end_level"`
+	Fired      map[string]bool `json:"fired"`
+}
+
+// lookForMilestones emits a milestone the first time during a ballot period that participation reaches the
+// quorum or a configured participation, and that the yay share reaches or drops below the supermajority
+func (t *TezosListener) lookForMilestones(ctx context.Context, block *tezos.Block, periodKind string) error {
+	hasBallots := false
+	for _, group := range block.Operations {
+		for _, op := range group {
+			if len(tezos.FilterBallotOps(op.Contents)) != 0 {
+				hasBallots = true
+			}
+		}
+	}
+
+	if !hasBallots {
+		return nil
+	}
+
+	log.Printf("TezosListener: Inspecting block %s for vote milestones.\n", block.Hash)
+
+	s, err := t.getMilestoneState(ctx, block.Header.Level)
+	if err != nil {
+		return err
+	}
+
+	b, err := getBallots(ctx, t.nodes.Service(), t.config.GetChainID(), block.Hash)
+	if err != nil {
+		return err
+	}
+
+	listings, err := getListings(ctx, t.nodes.Service(), t.config.GetChainID(), block.Hash)
+	if err != nil {
+		return err
+	}
+
+	result, err := t.newBallotResult(ctx, block.Header.Level, b, listings)
+	if err != nil {
+		return err
+	}
+
+	proposal, err := t.nodes.Service().GetCurrentProposals(ctx, t.config.GetChainID(), block.Hash)
+	if err != nil {
+		return err
+	}
+
+	reached := []*models.Milestone{}
+	fire := func(key, kind string, threshold float64) {
+		if s.Fired[key] {
+			return
+		}
+		s.Fired[key] = true
+		reached = append(reached, &models.Milestone{
+			BlockRef:     blockRef(block, ""),
+			Kind:         kind,
+			Period:       s.Period,
+			Phase:        normalizePeriodKind(periodKind),
+			ProposalHash: proposal,
+			Threshold:    threshold,
+			Result:       result,
+		})
+	}
+
+	for _, threshold := range t.config.GetParticipationMilestones() {
+		if result.PercentParticipation() >= threshold {
+			fire(fmt.Sprintf("%s_%s", models.MilestoneParticipation, strconv.FormatFloat(threshold, 'f', -1, 64)), models.MilestoneParticipation, threshold)
+		}
+	}
+
+	if result.QuorumReached() {
+		fire(models.MilestoneQuorum, models.MilestoneQuorum, 0)
+	}
+
+	if result.SupermajorityReached() {
+		fire(models.MilestoneSupermajority, models.MilestoneSupermajority, 0)
+	} else if s.Fired[models.MilestoneSupermajority] {
+		fire(models.MilestoneSupermajorityLost, models.MilestoneSupermajorityLost, 0)
+	}
+
+	if len(reached) == 0 {
+		return nil
+	}
+
+	// Save before publishing so a crash cannot publish a milestone twice
+	if err := t.store.Save(milestoneStateKey, s); err != nil {
+		return err
+	}
+
+	for _, m := range reached {
+		t.milestoneChan <- m
+	}
+	return nil
+}
+
+// getMilestoneState returns the milestone state of the ballot period containing level, loading it from the
+// store after a restart and resetting it when a new period begins
+func (t *TezosListener) getMilestoneState(ctx context.Context, level int) (*milestoneState, error) {
+	if t.milestones == nil {
+		var s milestoneState
+		found, err := t.store.Load(milestoneStateKey, &s)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			if s.Fired == nil {
+				s.Fired = make(map[string]bool)
+			}
+			t.milestones = &s
+		}
+	}
+
+	if s := t.milestones; s != nil && level >= s.StartLevel && level <= s.EndLevel {
+		return s, nil
+	}
+
+	period, err := getVotingPeriod(ctx, t.nodes.Service(), t.config.GetChainID(), level)
+	if err != nil {
+		return nil, err
+	}
+
+	t.milestones = &milestoneState{
+		Period:     period.Index,
+		StartLevel: period.StartLevel,
+		EndLevel:   period.EndLevel,
+		Fired:      make(map[string]bool),
+	}
+	return t.milestones, nil
+}
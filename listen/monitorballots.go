@@ -9,37 +9,42 @@ import (
 	"github.com/ecadlabs/tezos-bot/models"
 )
 
-func (t *TezosListener) lookForBallot(ctx context.Context, block *tezos.Block, periodKind tezos.PeriodKind) error {
+func (t *TezosListener) lookForBallot(ctx context.Context, block *tezos.Block, periodKind string) error {
 	hash := block.Hash
 	log.Printf("TezosListener: Inspecting block %s for new ballot operations.\n", block.Hash)
 
 	ballotOps := []*tezos.BallotOperationElem{}
+	opHashes := []string{}
 	for _, group := range block.Operations {
 		for _, op := range group {
-			ballotOps = append(ballotOps, tezos.FilterBallotOps(op.Contents)...)
+			for _, ballotOp := range tezos.FilterBallotOps(op.Contents) {
+				ballotOps = append(ballotOps, ballotOp)
+				opHashes = append(opHashes, op.Hash)
+			}
 		}
 	}
 
-	ballots, err := t.service.GetBallots(ctx, t.config.GetChainID(), hash)
+	if len(ballotOps) == 0 {
+		return nil
+	}
+
+	ballots, err := getBallots(ctx, t.nodes.Service(), t.config.GetChainID(), hash)
 
 	if err != nil {
 		return err
 	}
 
-	listings, err := t.service.GetBallotListings(ctx, t.config.GetChainID(), hash)
+	listings, err := getListings(ctx, t.nodes.Service(), t.config.GetChainID(), hash)
 
 	if err != nil {
 		return err
 	}
 
-	totalRolls := int64(0)
-	for _, entry := range listings {
-		totalRolls += entry.Rolls
-	}
+	totalVotingPower := listings.Total()
 
-	if totalRolls == 0 {
+	if totalVotingPower == 0 {
 		// Unlikely to occurs
-		return fmt.Errorf("No rolls found in this block")
+		return fmt.Errorf("No voting power found in this block")
 	}
 
 	quorum, err := t.getQuorum(ctx, hash)
@@ -48,37 +53,66 @@ func (t *TezosListener) lookForBallot(ctx context.Context, block *tezos.Block, p
 		return err
 	}
 
-	for _, ballotOp := range ballotOps {
-		rolls := int64(0)
-		for _, entry := range listings {
-			if entry.PKH == ballotOp.Source {
-				rolls = entry.Rolls
-			}
-		}
+	// Newer protocols no longer describe the voting period in the block metadata
+	period, err := getVotingPeriod(ctx, t.nodes.Service(), t.config.GetChainID(), block.Header.Level)
+
+	if err != nil {
+		return err
+	}
+
+	for i, ballotOp := range ballotOps {
 		ballot := &models.Ballot{
-			PKH:          ballotOp.Source,
-			Ballot:       ballotOp.Ballot,
-			ProposalHash: ballotOp.Proposal,
-			Rolls:        rolls,
-			Yay:          ballots.Yay,
-			Nay:          ballots.Nay,
-			Pass:         ballots.Pass,
-			Quorum:       quorum,
-			IsTesting:    periodKind.IsTestingVote(),
-			TotalRolls:   float64(totalRolls),
-			Period:       block.Metadata.Level.VotingPeriod,
+			BlockRef:         blockRef(block, opHashes[i]),
+			PKH:              ballotOp.Source,
+			Ballot:           ballotOp.Ballot,
+			ProposalHash:     ballotOp.Proposal,
+			VotingPower:      listings.PowerOf(ballotOp.Source),
+			Unit:             listings.Unit(),
+			Yay:              int64(ballots.Yay),
+			Nay:              int64(ballots.Nay),
+			Pass:             int64(ballots.Pass),
+			Quorum:           quorum,
+			Supermajority:    t.getSupermajority(),
+			IsTesting:        isExplorationPeriod(periodKind),
+			TotalVotingPower: float64(totalVotingPower),
+			Period:           period.Index,
+		}
+		if err := t.publishBallot(ctx, block, ballot); err != nil {
+			return err
 		}
-		t.votesChan <- ballot
 	}
 	return nil
 }
 
-func (t *TezosListener) getQuorum(ctx context.Context, block string) (float64, error) {
-	quorum, err := t.service.GetCurrentQuorum(ctx, t.config.GetChainID(), block)
+// getQuorum returns the quorum of the period at block along with the protocol bounds used to project the next one
+func (t *TezosListener) getQuorum(ctx context.Context, block string) (models.Quorum, error) {
+	quorum, err := t.nodes.Service().GetCurrentQuorum(ctx, t.config.GetChainID(), block)
+
+	if err != nil {
+		return models.Quorum{}, err
+	}
+
+	c, err := getConstants(ctx, t.nodes.Service(), t.config.GetChainID(), block)
 
 	if err != nil {
-		return 0, err
+		return models.Quorum{}, err
 	}
 
-	return float64(quorum) / 100, nil
+	ema := 0
+	if c.QuorumMax > c.QuorumMin {
+		if ema, err = getParticipationEMA(ctx, t.nodes.Service(), t.config.GetChainID(), block); err != nil {
+			return models.Quorum{}, err
+		}
+	}
+
+	return models.NewQuorum(int64(quorum), int64(ema), int64(c.QuorumMin), int64(c.QuorumMax)), nil
+}
+
+// getSupermajority returns the supermajority in percent. Protocols hard code it rather than exposing it in
+// their constants, the configured value is used when set
+func (t *TezosListener) getSupermajority() float64 {
+	if s := t.config.GetSupermajority(); s > 0 {
+		return s
+	}
+	return models.DefaultSupermajority
 }
@@ -0,0 +1,44 @@
+package listen
+
+import (
+	"context"
+	"time"
+
+	tezos "github.com/ecadlabs/go-tezos"
+	"github.com/ecadlabs/tezos-bot/models"
+)
+
+// lookForHead emits every block with its voting period, the period tracked for period changes is reused when up to date
+func (t *TezosListener) lookForHead(ctx context.Context, block *tezos.Block) error {
+	level := block.Header.Level
+	contains := func(p *periodState) bool {
+		return p != nil && level >= p.period.StartLevel && level <= p.period.EndLevel
+	}
+
+	p := t.period
+	if !contains(p) {
+		if !contains(t.head) {
+			current, err := t.getPeriodState(ctx, block)
+			if err != nil {
+				return err
+			}
+			t.head = current
+		}
+		p = t.head
+	}
+
+	ref := blockRef(block, "")
+	t.headChan <- &models.Head{
+		BlockRef: ref,
+		Period: &models.VotingPeriod{
+			BlockRef:     ref,
+			Index:        p.period.Index,
+			Kind:         normalizePeriodKind(p.period.Kind),
+			StartLevel:   p.period.StartLevel,
+			EndLevel:     p.period.EndLevel,
+			EstimatedEnd: block.Header.Timestamp.Add(time.Duration(p.period.EndLevel-level) * p.blockDelay),
+			ProposalHash: p.proposal,
+		},
+	}
+	return nil
+}
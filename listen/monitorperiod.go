@@ -0,0 +1,220 @@
+package listen
+
+import (
+	"context"
+	"log"
+	"sort"
+	"strconv"
+	"time"
+
+	tezos "github.com/ecadlabs/go-tezos"
+	"github.com/ecadlabs/tezos-bot/models"
+)
+
+// periodState is the voting period of the last block inspected
+type periodState struct {
+	period     *votingPeriod
+	blockDelay time.Duration
+	proposal   string
+	// countdowns already emitted during the period
+	countdowns map[time.Duration]bool
+}
+
+func (t *TezosListener) lookForPeriodChange(ctx context.Context, block *tezos.Block) error {
+	level := block.Header.Level
+	if p := t.period; p != nil && level >= p.period.StartLevel && level <= p.period.EndLevel {
+		return t.lookForActivationCountdown(block)
+	}
+
+	log.Printf("TezosListener: Inspecting block %s for voting period changes.\n", block.Hash)
+
+	current, err := t.getPeriodState(ctx, block)
+	if err != nil {
+		return err
+	}
+	period, proposal := current.period, current.proposal
+
+	// Only announce the period when the listener actually saw it begin. Periods skipped during a backfill have
+	// no transition to report, the previous period seen is not the one which just ended
+	previous := t.period
+	contiguous := previous != nil && previous.period.Index == period.Index-1 && previous.period.EndLevel+1 == period.StartLevel
+	entered := contiguous || level == period.StartLevel
+	t.period = current
+
+	if contiguous {
+		if err := t.emitPeriodTransition(ctx, block, previous, period, proposal); err != nil {
+			return err
+		}
+	}
+
+	if entered {
+		t.periodChan <- &models.VotingPeriod{
+			BlockRef:     blockRef(block, ""),
+			Index:        period.Index,
+			Kind:         normalizePeriodKind(period.Kind),
+			StartLevel:   period.StartLevel,
+			EndLevel:     period.EndLevel,
+			EstimatedEnd: t.estimateLevelTime(block, period.EndLevel),
+			ProposalHash: proposal,
+		}
+	}
+
+	return t.lookForActivationCountdown(block)
+}
+
+// getPeriodState returns the voting period containing block, with the proposal under evaluation if any
+func (t *TezosListener) getPeriodState(ctx context.Context, block *tezos.Block) (*periodState, error) {
+	level := block.Header.Level
+	period, err := getVotingPeriod(ctx, t.nodes.Service(), t.config.GetChainID(), level)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := getConstants(ctx, t.nodes.Service(), t.config.GetChainID(), strconv.Itoa(level))
+	if err != nil {
+		return nil, err
+	}
+
+	proposal := ""
+	if !isProposalPeriod(period.Kind) {
+		proposal, err = t.nodes.Service().GetCurrentProposals(ctx, t.config.GetChainID(), block.Hash)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &periodState{
+		period:     period,
+		blockDelay: c.BlockDelay(),
+		proposal:   proposal,
+		countdowns: make(map[time.Duration]bool),
+	}, nil
+}
+
+// emitPeriodTransition emits the outcome of the previous period on the first block of the next one
+func (t *TezosListener) emitPeriodTransition(ctx context.Context, block *tezos.Block, previous *periodState, next *votingPeriod, nextProposal string) error {
+	transition := &models.PeriodTransition{
+		BlockRef:     blockRef(block, ""),
+		FromIndex:    previous.period.Index,
+		FromKind:     normalizePeriodKind(previous.period.Kind),
+		ToIndex:      next.Index,
+		ToKind:       normalizePeriodKind(next.Kind),
+		ProposalHash: previous.proposal,
+	}
+
+	if isProposalPeriod(previous.period.Kind) {
+		// The winning proposal is the one under evaluation in the next period
+		transition.ProposalHash = nextProposal
+	}
+
+	if isBallotPeriod(previous.period.Kind) {
+		result, err := t.getBallotResult(ctx, previous.period.EndLevel)
+		if err != nil {
+			return err
+		}
+		transition.Result = result
+	}
+
+	t.transitionChan <- transition
+	return nil
+}
+
+// getBallotResult returns the final tallies of the ballot period ending at endLevel
+func (t *TezosListener) getBallotResult(ctx context.Context, endLevel int) (*models.BallotResult, error) {
+	level, b, err := t.getFinalBallots(ctx, endLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	listings, err := getListings(ctx, t.nodes.Service(), t.config.GetChainID(), strconv.Itoa(level))
+	if err != nil {
+		return nil, err
+	}
+
+	return t.newBallotResult(ctx, level, b, listings)
+}
+
+// getFinalBallots returns the final tallies of the ballot period ending at endLevel and the level they were read at.
+// Older protocols already reset the ballots in the context of the last block of a period, the final
+// tallies are then read from the block before
+func (t *TezosListener) getFinalBallots(ctx context.Context, endLevel int) (int, *ballots, error) {
+	level := endLevel
+	b, err := getBallots(ctx, t.nodes.Service(), t.config.GetChainID(), strconv.Itoa(level))
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if b.Yay+b.Nay+b.Pass == 0 {
+		level = endLevel - 1
+		if b, err = getBallots(ctx, t.nodes.Service(), t.config.GetChainID(), strconv.Itoa(level)); err != nil {
+			return 0, nil, err
+		}
+	}
+	return level, b, nil
+}
+
+// newBallotResult builds the result of a ballot period from the tallies and listings read at level
+func (t *TezosListener) newBallotResult(ctx context.Context, level int, b *ballots, listings listings) (*models.BallotResult, error) {
+	quorum, err := t.getQuorum(ctx, strconv.Itoa(level))
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.BallotResult{
+		Yay:              int64(b.Yay),
+		Nay:              int64(b.Nay),
+		Pass:             int64(b.Pass),
+		TotalVotingPower: listings.Total(),
+		Unit:             listings.Unit(),
+		Quorum:           quorum,
+		Supermajority:    t.getSupermajority(),
+	}, nil
+}
+
+// lookForActivationCountdown emits a countdown each time the activation of the adopted proposal gets
+// closer than one of the configured durations
+func (t *TezosListener) lookForActivationCountdown(block *tezos.Block) error {
+	p := t.period
+	if !isAdoptionPeriod(p.period.Kind) {
+		return nil
+	}
+
+	activationLevel := p.period.EndLevel + 1
+	remaining := activationLevel - block.Header.Level
+	eta := time.Duration(remaining) * p.blockDelay
+
+	thresholds := append([]time.Duration{}, t.config.GetActivationCountdown()...)
+	sort.Slice(thresholds, func(i, j int) bool { return thresholds[i] < thresholds[j] })
+
+	// Only the closest threshold is announced when several are crossed at once
+	for _, threshold := range thresholds {
+		if eta > threshold || p.countdowns[threshold] {
+			continue
+		}
+		for _, other := range thresholds {
+			if other >= threshold {
+				p.countdowns[other] = true
+			}
+		}
+		t.countdownChan <- &models.ActivationCountdown{
+			BlockRef:            blockRef(block, ""),
+			ProposalHash:        p.proposal,
+			ActivationLevel:     activationLevel,
+			BlocksRemaining:     remaining,
+			TimeRemaining:       eta,
+			EstimatedActivation: t.estimateLevelTime(block, activationLevel),
+		}
+		break
+	}
+
+	return nil
+}
+
+// estimateLevelTime estimates when level will be baked based on the expected time between blocks
+func (t *TezosListener) estimateLevelTime(block *tezos.Block, level int) time.Time {
+	delay := time.Minute
+	if t.period != nil {
+		delay = t.period.blockDelay
+	}
+	return block.Header.Timestamp.Add(time.Duration(level-block.Header.Level) * delay)
+}
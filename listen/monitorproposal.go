@@ -12,8 +12,23 @@ import (
 func (t *TezosListener) lookForProposal(ctx context.Context, block *tezos.Block) error {
 	log.Printf("TezosListener: Inspecting block %s for new proposal operations.\n", block.Hash)
 
+	proposalOps := []*tezos.ProposalOperationElem{}
+	opHashes := []string{}
+	for _, group := range block.Operations {
+		for _, op := range group {
+			for _, proposalOp := range tezos.FilterProposalOps(op.Contents) {
+				proposalOps = append(proposalOps, proposalOp)
+				opHashes = append(opHashes, op.Hash)
+			}
+		}
+	}
+
+	if len(proposalOps) == 0 {
+		return nil
+	}
+
 	// Retrieve proposals for the current phase
-	existingProposals, err := t.service.GetProposals(ctx, t.config.GetChainID(), fmt.Sprintf("%d", block.Header.Level-1))
+	existingProposals, err := getProposals(ctx, t.nodes.Service(), t.config.GetChainID(), fmt.Sprintf("%d", block.Header.Level-1))
 
 	if err != nil {
 		return err
@@ -28,46 +43,29 @@ func (t *TezosListener) lookForProposal(ctx context.Context, block *tezos.Block)
 		return false
 	}
 
-	if err != nil {
-		return err
-	}
-
-	proposalOps := []*tezos.ProposalOperationElem{}
-	for _, group := range block.Operations {
-		for _, op := range group {
-			proposalOps = append(proposalOps, tezos.FilterProposalOps(op.Contents)...)
-		}
-	}
-
-	listings, err := t.service.GetBallotListings(ctx, t.config.GetChainID(), block.Hash)
+	listings, err := getListings(ctx, t.nodes.Service(), t.config.GetChainID(), block.Hash)
 
 	if err != nil {
 		return err
 	}
 
-	totalRolls := int64(0)
-	for _, entry := range listings {
-		totalRolls += entry.Rolls
-	}
+	totalVotingPower := listings.Total()
 
-	if totalRolls == 0 {
+	if totalVotingPower == 0 {
 		// Unlikely to occurs
-		return fmt.Errorf("No rolls found in this block")
+		return fmt.Errorf("No voting power found in this block")
 	}
 
-	for _, proposalOp := range proposalOps {
+	for i, proposalOp := range proposalOps {
 		for _, proposal := range proposalOp.Proposals {
-			rolls := int64(0)
-			for _, entry := range listings {
-				if entry.PKH == proposalOp.Source {
-					rolls = entry.Rolls
-				}
-			}
 			p := &models.Proposal{
-				ProposalHash: proposal,
-				PKH:          proposalOp.Source,
-				Period:       proposalOp.Period,
-				Rolls:        rolls,
+				BlockRef:         blockRef(block, opHashes[i]),
+				ProposalHash:     proposal,
+				PKH:              proposalOp.Source,
+				Period:           proposalOp.Period,
+				VotingPower:      listings.PowerOf(proposalOp.Source),
+				TotalVotingPower: totalVotingPower,
+				Unit:             listings.Unit(),
 			}
 
 			if !proposalExists(proposal) {
@@ -2,97 +2,146 @@ package listen
 
 import (
 	"context"
-	"fmt"
 	"log"
 	"sort"
+	"strconv"
 
 	tezos "github.com/ecadlabs/go-tezos"
 	"github.com/ecadlabs/tezos-bot/models"
 )
 
-func (t *TezosListener) retrieveTopNProposal(ctx context.Context, level int, limit int) ([]*tezos.Proposal, error) {
-	// Retrieve proposals for the current phase
-	proposals, err := t.service.GetProposals(ctx, t.config.GetChainID(), fmt.Sprintf("%d", level-1))
+// retrieveTopNProposal returns the limit proposals with the most voting power in the context of blockID
+func (t *TezosListener) retrieveTopNProposal(ctx context.Context, blockID string, limit int) ([]*proposal, error) {
+	proposals, err := getProposals(ctx, t.nodes.Service(), t.config.GetChainID(), blockID)
 
 	if err != nil {
 		return nil, err
 	}
 	sort.Slice(proposals, func(i, j int) bool {
-		return proposals[i].SupporterCount > proposals[j].SupporterCount
+		return proposals[i].Power > proposals[j].Power
 	})
 
-	topN := []*tezos.Proposal{}
-
-	if len(proposals) > 0 {
-		for i := range proposals {
-			if i+1 > limit {
-				return topN, nil
-			}
+	if len(proposals) > limit {
+		proposals = proposals[:limit]
+	}
+	return proposals, nil
+}
 
-			topN = append(topN, proposals[i])
+// getProposalCycles returns the voting period containing level and its cycles, they are only fetched again once the period changes
+func (t *TezosListener) getProposalCycles(ctx context.Context, level int) (*cycleState, error) {
+	if t.proposalCycles == nil || !t.proposalCycles.contains(level) {
+		cycles, err := getCycleState(ctx, t.nodes.Service(), t.config.GetChainID(), level)
+		if err != nil {
+			return nil, err
 		}
+		t.proposalCycles = cycles
 	}
-
-	return topN, nil
+	return t.proposalCycles, nil
 }
 
+// lookForWinningProposal emits the proposal with the most voting power at the end of a proposal period, on
+// the first block of the exploration period evaluating it
 func (t *TezosListener) lookForWinningProposal(ctx context.Context, block *tezos.Block) error {
-	periodLength := 4096 * 8
-	isLastBlockOfVotingPeriod := block.Header.Level%periodLength == 0
-
-	if isLastBlockOfVotingPeriod {
-		log.Printf("TezosListener: Inspecting block %s for winning proposal.\n", block.Hash)
-		// Retrieve proposals for the current phase
-		proposals, err := t.retrieveTopNProposal(ctx, block.Header.Level, 1)
+	level := block.Header.Level
+	cycles, err := t.getProposalCycles(ctx, level)
+	if err != nil {
+		return err
+	}
+	if level != cycles.period.StartLevel {
+		return nil
+	}
 
-		if err != nil {
+	log.Printf("TezosListener: Inspecting block %s for winning proposal.\n", block.Hash)
+	last := level - 1
+	proposals, err := t.retrieveTopNProposal(ctx, strconv.Itoa(last), 1)
+	if err != nil {
+		return err
+	}
+	// Older protocols already reset the proposals in the context of the last block of a period
+	if len(proposals) == 0 {
+		if proposals, err = t.retrieveTopNProposal(ctx, strconv.Itoa(last-1), 1); err != nil {
 			return err
 		}
+	}
 
-		for i := range proposals {
-			// Publish winning proposal
-			t.winningProposalChan <- &models.ProposalSummary{
-				Proposal: *proposals[i],
-				Cycle:    (block.Header.Level / 4096) - 1,
-			}
+	listings, err := getListings(ctx, t.nodes.Service(), t.config.GetChainID(), strconv.Itoa(last))
+	if err != nil {
+		return err
+	}
+
+	cycle, err := getCycle(ctx, t.nodes.Service(), t.config.GetChainID(), strconv.Itoa(last))
+	if err != nil {
+		return err
+	}
+
+	for i := range proposals {
+		// Publish winning proposal
+		t.winningProposalChan <- &models.ProposalSummary{
+			BlockRef:         blockRef(block, ""),
+			ProposalHash:     proposals[i].ProposalHash,
+			VotingPower:      proposals[i].Power,
+			TotalVotingPower: listings.Total(),
+			Unit:             listings.Unit(),
+			Cycle:            cycle,
 		}
 	}
 
 	return nil
 }
 
+// lookForProposalSummary emits the proposals with the most voting power, and the upvotes they received
+// during the cycle, at the end of each cycle of a proposal period
 func (t *TezosListener) lookForProposalSummary(ctx context.Context, block *tezos.Block) error {
-	if block.Header.Level%4096 == 0 {
-		log.Printf("TezosListener: Inspecting block %s for proposal summary.\n", block.Hash)
-		// Retrieve proposals for the current phase
-		proposals, err := t.retrieveTopNProposal(ctx, block.Header.Level, 3)
-
-		if err != nil {
-			return err
-		}
+	level := block.Header.Level
+	cycles, err := t.getProposalCycles(ctx, level)
+	if err != nil {
+		return err
+	}
+	if !cycles.isCycleEnd(level) {
+		return nil
+	}
 
-		previousProposals, err := t.service.GetProposals(ctx, t.config.GetChainID(), fmt.Sprintf("%d", block.Header.Level-4096))
+	log.Printf("TezosListener: Inspecting block %s for proposal summary.\n", block.Hash)
+	proposals, err := t.retrieveTopNProposal(ctx, block.Hash, 3)
+	if err != nil {
+		return err
+	}
 
-		if err != nil {
+	// The proposals of the previous period do not count in the first cycle
+	previousProposals := []*proposal{}
+	if previous := level - cycles.blocksPerCycle; previous >= cycles.period.StartLevel {
+		if previousProposals, err = getProposals(ctx, t.nodes.Service(), t.config.GetChainID(), strconv.Itoa(previous)); err != nil {
 			return err
 		}
+	}
 
-		for i := range proposals {
-			previousSupporters := 0
-			for _, prev := range previousProposals {
-				if prev.ProposalHash == proposals[i].ProposalHash {
-					previousSupporters = prev.SupporterCount
-				}
-			}
+	listings, err := getListings(ctx, t.nodes.Service(), t.config.GetChainID(), block.Hash)
+	if err != nil {
+		return err
+	}
 
-			newSupporters := proposals[i].SupporterCount - previousSupporters
+	cycle, err := getCycle(ctx, t.nodes.Service(), t.config.GetChainID(), block.Hash)
+	if err != nil {
+		return err
+	}
 
-			t.proposalSummaryChan <- &models.ProposalSummary{
-				Proposal:      *proposals[i],
-				Cycle:         (block.Header.Level / 4096) - 1,
-				NewSupporters: newSupporters,
+	for i := range proposals {
+		previousPower := int64(0)
+		for _, prev := range previousProposals {
+			if prev.ProposalHash == proposals[i].ProposalHash {
+				previousPower = prev.Power
 			}
 		}
+
+		t.proposalSummaryChan <- &models.ProposalSummary{
+			BlockRef:         blockRef(block, ""),
+			ProposalHash:     proposals[i].ProposalHash,
+			VotingPower:      proposals[i].Power,
+			NewVotingPower:   proposals[i].Power - previousPower,
+			TotalVotingPower: listings.Total(),
+			Unit:             listings.Unit(),
+			Cycle:            cycle,
+		}
 	}
 
 	return nil
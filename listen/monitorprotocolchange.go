@@ -5,6 +5,7 @@ import (
 	"log"
 
 	tezos "github.com/ecadlabs/go-tezos"
+	"github.com/ecadlabs/tezos-bot/models"
 )
 
 func (t *TezosListener) lookForProtocolChange(ctx context.Context, block *tezos.Block) error {
@@ -13,7 +14,7 @@ func (t *TezosListener) lookForProtocolChange(ctx context.Context, block *tezos.
 	pred := lastBlock
 	if lastBlock == nil {
 		predHash := block.Header.Predecessor
-		b, err := t.service.GetBlock(ctx, t.config.GetChainID(), predHash)
+		b, err := t.nodes.Service().GetBlock(ctx, t.config.GetChainID(), predHash)
 		if err != nil {
 			return err
 		}
@@ -21,7 +22,10 @@ func (t *TezosListener) lookForProtocolChange(ctx context.Context, block *tezos.
 	}
 
 	if block.Protocol != pred.Protocol {
-		t.protoChan <- block.Protocol
+		t.protoChan <- &models.ProtocolChange{
+			BlockRef: blockRef(block, ""),
+			Protocol: block.Protocol,
+		}
 	}
 
 	lastBlock = block
@@ -0,0 +1,119 @@
+package listen
+
+import (
+	"context"
+	"log"
+	"sort"
+	"strconv"
+
+	tezos "github.com/ecadlabs/go-tezos"
+	"github.com/ecadlabs/tezos-bot/models"
+)
+
+// finalReportTopVoters is the number of largest yay and nay voters listed in a final report
+const finalReportTopVoters = 5
+
+// reportState is the voting period of the last block inspected for a final report
+type reportState struct {
+	period   *votingPeriod
+	reported bool
+}
+
+// lookForFinalReport emits the final report of a ballot period on its last block
+func (t *TezosListener) lookForFinalReport(ctx context.Context, block *tezos.Block) error {
+	level := block.Header.Level
+	if r := t.report; r == nil || level < r.period.StartLevel || level > r.period.EndLevel {
+		period, err := getVotingPeriod(ctx, t.nodes.Service(), t.config.GetChainID(), level)
+		if err != nil {
+			return err
+		}
+		t.report = &reportState{period: period}
+	}
+
+	r := t.report
+	if r.reported || level != r.period.EndLevel || !isBallotPeriod(r.period.Kind) {
+		return nil
+	}
+
+	log.Printf("TezosListener: Building final report of period %d on block %s.\n", r.period.Index, block.Hash)
+
+	report, err := t.getFinalReport(ctx, r.period, block)
+	if err != nil {
+		return err
+	}
+
+	r.reported = true
+	t.finalReportChan <- report
+	return nil
+}
+
+// getFinalReport returns the report of the ballot period ending at the given block
+func (t *TezosListener) getFinalReport(ctx context.Context, period *votingPeriod, block *tezos.Block) (*models.FinalReport, error) {
+	level, b, err := t.getFinalBallots(ctx, period.EndLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	listings, err := getListings(ctx, t.nodes.Service(), t.config.GetChainID(), strconv.Itoa(level))
+	if err != nil {
+		return nil, err
+	}
+
+	ballotList, err := getBallotList(ctx, t.nodes.Service(), t.config.GetChainID(), strconv.Itoa(level))
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := t.newBallotResult(ctx, level, b, listings)
+	if err != nil {
+		return nil, err
+	}
+
+	proposal, err := t.nodes.Service().GetCurrentProposals(ctx, t.config.GetChainID(), block.Hash)
+	if err != nil {
+		return nil, err
+	}
+
+	cast := make(map[string]string, len(ballotList))
+	for _, entry := range ballotList {
+		cast[entry.PKH] = entry.Ballot
+	}
+
+	report := &models.FinalReport{
+		BlockRef:     blockRef(block, ""),
+		Period:       period.Index,
+		Kind:         normalizePeriodKind(period.Kind),
+		ProposalHash: proposal,
+		Result:       result,
+		Voters:       len(ballotList),
+	}
+
+	for _, entry := range listings {
+		voter := models.Voter{PKH: entry.PKH, VotingPower: entry.Power()}
+		switch ballot, ok := cast[entry.PKH]; {
+		case !ok:
+			report.NonVoters++
+			report.NonVotersPower += voter.VotingPower
+		case ballot == "yay":
+			report.TopYay = append(report.TopYay, voter)
+		case ballot == "nay":
+			report.TopNay = append(report.TopNay, voter)
+		}
+	}
+
+	report.TopYay = topVoters(report.TopYay, finalReportTopVoters)
+	report.TopNay = topVoters(report.TopNay, finalReportTopVoters)
+
+	return report, nil
+}
+
+// topVoters returns the n voters with the most voting power
+func topVoters(voters []models.Voter, n int) []models.Voter {
+	sort.SliceStable(voters, func(i, j int) bool {
+		return voters[i].VotingPower > voters[j].VotingPower
+	})
+	if len(voters) > n {
+		voters = voters[:n]
+	}
+	return voters
+}
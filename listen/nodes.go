@@ -0,0 +1,240 @@
+package listen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+
+	tezos "github.com/ecadlabs/go-tezos"
+)
+
+// ErrNoNode is returned when a node pool is created without any rpc url
+var ErrNoNode = errors.New("No rpc url configured")
+
+type node struct {
+	url     string
+	service *tezos.Service
+	level   int
+	err     error
+}
+
+// NodePool keeps track of the health of several tezos nodes and of the one currently in use.
+// Nodes are listed by order of preference
+type NodePool struct {
+	mu       sync.RWMutex
+	nodes    []*node
+	active   int
+	maxLag   int
+	status   StatusReporter
+	switched chan struct{}
+}
+
+// NewNodePool create a new NodePool, the first url is the preferred node
+func NewNodePool(urls []string, maxLag int, status StatusReporter) (*NodePool, error) {
+	if len(urls) == 0 {
+		return nil, ErrNoNode
+	}
+
+	nodes := make([]*node, len(urls))
+	for i, url := range urls {
+		client, err := tezos.NewRPCClient(http.DefaultClient, url)
+		if err != nil {
+			return nil, err
+		}
+		nodes[i] = &node{
+			url:     url,
+			service: &tezos.Service{Client: client},
+		}
+	}
+
+	p := &NodePool{
+		nodes:    nodes,
+		maxLag:   maxLag,
+		status:   status,
+		switched: make(chan struct{}, 1),
+	}
+	p.report()
+
+	return p, nil
+}
+
+// Service returns the service of the node currently in use
+func (p *NodePool) Service() *tezos.Service {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.nodes[p.active].service
+}
+
+// URL returns the url of the node currently in use
+func (p *NodePool) URL() string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.nodes[p.active].url
+}
+
+// Switched is notified every time the node in use changes
+func (p *NodePool) Switched() <-chan struct{} {
+	return p.switched
+}
+
+// Failover marks the node in use as failing and moves to the next one
+func (p *NodePool) Failover(err error) {
+	p.mu.Lock()
+	current := p.nodes[p.active]
+	current.err = err
+	if len(p.nodes) == 1 {
+		p.mu.Unlock()
+		p.report()
+		return
+	}
+	p.active = (p.active + 1) % len(p.nodes)
+	next := p.nodes[p.active]
+	p.mu.Unlock()
+
+	log.Printf("NodePool: failing over from %s to %s: %s\n", current.url, next.url, err.Error())
+	p.notify()
+	p.report()
+}
+
+// Do runs fn against the node in use, failing over to the next nodes until one succeeds or all of them failed
+func (p *NodePool) Do(ctx context.Context, fn func(service *tezos.Service) error) error {
+	var err error
+	for i := 0; i < len(p.nodes); i++ {
+		if err = fn(p.Service()); err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+		p.Failover(err)
+	}
+	return err
+}
+
+// Refresh polls the head of every node and switches to the most preferred node which is reachable and
+// does not fall behind the others by more than the allowed lag
+func (p *NodePool) Refresh(ctx context.Context, chainID string) {
+	var wg sync.WaitGroup
+	levels := make([]int, len(p.nodes))
+	errs := make([]error, len(p.nodes))
+	for i, n := range p.nodes {
+		wg.Add(1)
+		go func(i int, n *node) {
+			defer wg.Done()
+			header, err := getBlockHeader(ctx, n.service, chainID, HEAD_BLOCK)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			levels[i] = header.Level
+		}(i, n)
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return
+	}
+
+	p.mu.Lock()
+	maxLevel := 0
+	for i, n := range p.nodes {
+		n.err = errs[i]
+		if errs[i] == nil {
+			n.level = levels[i]
+			if n.level > maxLevel {
+				maxLevel = n.level
+			}
+		}
+	}
+
+	best := -1
+	for i, n := range p.nodes {
+		if n.err == nil && maxLevel-n.level <= p.maxLag {
+			best = i
+			break
+		}
+	}
+
+	previous := p.nodes[p.active]
+	changed := best != -1 && best != p.active
+	if changed {
+		p.active = best
+	}
+	next := p.nodes[p.active]
+	p.mu.Unlock()
+
+	if changed {
+		reason := "preferred node is available again"
+		if previous.err != nil {
+			reason = previous.err.Error()
+		} else if maxLevel-previous.level > p.maxLag {
+			reason = fmt.Sprintf("%d blocks behind", maxLevel-previous.level)
+		}
+		log.Printf("NodePool: switching from %s to %s: %s\n", previous.url, next.url, reason)
+		p.notify()
+	}
+	p.report()
+}
+
+// CrossCheck verifies that the nodes which already reached level agree on its block hash. Nodes that are
+// unreachable or have not reached the level yet are ignored, the block is rejected unless the nodes which
+// agree with hash, including the node in use, outnumber the ones which disagree
+func (p *NodePool) CrossCheck(ctx context.Context, chainID string, level int, hash string) error {
+	p.mu.RLock()
+	active := p.active
+	p.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	hashes := make([]string, len(p.nodes))
+	for i, n := range p.nodes {
+		if i == active {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, n *node) {
+			defer wg.Done()
+			header, err := getBlockHeader(ctx, n.service, chainID, strconv.Itoa(level))
+			if err == nil {
+				hashes[i] = header.Hash
+			}
+		}(i, n)
+	}
+	wg.Wait()
+
+	agree, disagree := 1, 0
+	for i, h := range hashes {
+		if i == active || h == "" {
+			continue
+		}
+		if h == hash {
+			agree++
+		} else {
+			disagree++
+		}
+	}
+
+	if disagree >= agree {
+		return fmt.Errorf("Block %s at level %d is not confirmed by other nodes (%d agree, %d disagree)", hash, level, agree, disagree)
+	}
+
+	return nil
+}
+
+func (p *NodePool) notify() {
+	select {
+	case p.switched <- struct{}{}:
+	default:
+	}
+}
+
+func (p *NodePool) report() {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	for i, n := range p.nodes {
+		p.status.SetNode(n.url, n.level, i == p.active, n.err)
+	}
+}
@@ -0,0 +1,190 @@
+package listen
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	tezos "github.com/ecadlabs/go-tezos"
+	"github.com/ecadlabs/tezos-bot/health"
+)
+
+// fakeNode serves the block headers of a chain up to its head level, blocks are hashed as fork followed by
+// their level so that nodes on different forks disagree
+type fakeNode struct {
+	*httptest.Server
+	mu    sync.Mutex
+	level int
+	fork  string
+	down  bool
+}
+
+func newFakeNode(level int, fork string) *fakeNode {
+	n := &fakeNode{level: level, fork: fork}
+	n.Server = httptest.NewServer(http.HandlerFunc(n.serveHeader))
+	return n
+}
+
+func (n *fakeNode) set(level int, down bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.level, n.down = level, down
+}
+
+func (n *fakeNode) serveHeader(w http.ResponseWriter, r *http.Request) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.down {
+		http.Error(w, "node is down", http.StatusInternalServerError)
+		return
+	}
+	id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/chains/main/blocks/"), "/header")
+	level := n.level
+	if id != HEAD_BLOCK {
+		var err error
+		if level, err = strconv.Atoi(id); err != nil || level > n.level {
+			http.NotFound(w, r)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"level": level, "hash": n.fork + strconv.Itoa(level)})
+}
+
+func newTestPool(t *testing.T, maxLag int, nodes ...*fakeNode) *NodePool {
+	urls := []string{}
+	for _, n := range nodes {
+		urls = append(urls, n.URL)
+	}
+	pool, err := NewNodePool(urls, maxLag, health.NewStatus())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pool
+}
+
+func TestNodePoolDo(t *testing.T) {
+	a, b := newFakeNode(100, "B"), newFakeNode(100, "B")
+	defer a.Close()
+	defer b.Close()
+	a.set(100, true)
+	pool := newTestPool(t, 2, a, b)
+
+	ctx := context.Background()
+	var header *blockHeader
+	err := pool.Do(ctx, func(service *tezos.Service) (err error) {
+		header, err = getBlockHeader(ctx, service, "main", HEAD_BLOCK)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Do() with a failing preferred node: %s", err)
+	}
+	if header.Level != 100 {
+		t.Errorf("Do() read level %d, want 100", header.Level)
+	}
+	if pool.URL() != b.URL {
+		t.Errorf("Do() left the pool on %s, want the second node %s", pool.URL(), b.URL)
+	}
+
+	b.set(100, true)
+	err = pool.Do(ctx, func(service *tezos.Service) error {
+		_, err := getBlockHeader(ctx, service, "main", HEAD_BLOCK)
+		return err
+	})
+	if err == nil {
+		t.Errorf("Do() succeeded with every node down")
+	}
+}
+
+func TestNodePoolRefresh(t *testing.T) {
+	a, b := newFakeNode(100, "B"), newFakeNode(100, "B")
+	defer a.Close()
+	defer b.Close()
+	pool := newTestPool(t, 2, a, b)
+	nodes := []*fakeNode{a, b}
+
+	steps := []struct {
+		name       string
+		levelA     int
+		downA      bool
+		levelB     int
+		downB      bool
+		wantActive int
+		wantSwitch bool
+	}{
+		{name: "both in sync", levelA: 100, levelB: 100, wantActive: 0},
+		{name: "preferred node down", levelA: 100, downA: true, levelB: 101, wantActive: 1, wantSwitch: true},
+		{name: "preferred node back", levelA: 102, levelB: 102, wantActive: 0, wantSwitch: true},
+		{name: "preferred node too far behind", levelA: 100, levelB: 103, wantActive: 1, wantSwitch: true},
+		{name: "preferred node within the lag", levelA: 101, levelB: 103, wantActive: 0, wantSwitch: true},
+		{name: "every node down", levelA: 101, downA: true, levelB: 103, downB: true, wantActive: 0},
+	}
+
+	for _, step := range steps {
+		a.set(step.levelA, step.downA)
+		b.set(step.levelB, step.downB)
+		pool.Refresh(context.Background(), "main")
+
+		if got, want := pool.URL(), nodes[step.wantActive].URL; got != want {
+			t.Errorf("%s: active node = %s, want %s", step.name, got, want)
+		}
+		switched := false
+		select {
+		case <-pool.Switched():
+			switched = true
+		default:
+		}
+		if switched != step.wantSwitch {
+			t.Errorf("%s: switched = %v, want %v", step.name, switched, step.wantSwitch)
+		}
+	}
+}
+
+func TestNodePoolCrossCheck(t *testing.T) {
+	type peer struct {
+		level int
+		fork  string
+		down  bool
+	}
+
+	// The node in use is on fork B at level 100, the block checked is B100
+	tests := []struct {
+		name    string
+		peers   []peer
+		wantErr bool
+	}{
+		{name: "single node", peers: nil},
+		{name: "all agree", peers: []peer{{100, "B", false}, {100, "B", false}}},
+		{name: "majority agrees", peers: []peer{{100, "B", false}, {100, "C", false}}},
+		{name: "tie", peers: []peer{{100, "C", false}, {100, "B", true}}, wantErr: true},
+		{name: "disagreement with a node behind", peers: []peer{{100, "C", false}, {99, "B", false}}, wantErr: true},
+		{name: "peers behind", peers: []peer{{99, "B", false}, {98, "C", false}}},
+		{name: "peers down", peers: []peer{{100, "B", true}, {100, "C", true}}},
+		{name: "all disagree", peers: []peer{{100, "C", false}, {100, "C", false}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodes := []*fakeNode{newFakeNode(100, "B")}
+			for _, p := range tt.peers {
+				n := newFakeNode(p.level, p.fork)
+				n.set(p.level, p.down)
+				nodes = append(nodes, n)
+			}
+			for _, n := range nodes {
+				defer n.Close()
+			}
+
+			err := newTestPool(t, 2, nodes...).CrossCheck(context.Background(), "main", 100, "B100")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CrossCheck() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
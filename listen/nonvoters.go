@@ -0,0 +1,85 @@
+package listen
+
+import (
+	"context"
+	"log"
+
+	tezos "github.com/ecadlabs/go-tezos"
+	"github.com/ecadlabs/tezos-bot/models"
+)
+
+// lookForNonVoters emits a report of the delegates who did not vote yet at the end of each cycle of a ballot
+// period, or only before its last cycle, when bakers still have time to vote
+func (t *TezosListener) lookForNonVoters(ctx context.Context, block *tezos.Block, periodKind string) error {
+	level := block.Header.Level
+	if t.nonVoters == nil || !t.nonVoters.contains(level) {
+		s, err := getCycleState(ctx, t.nodes.Service(), t.config.GetChainID(), level)
+		if err != nil {
+			return err
+		}
+		t.nonVoters = s
+	}
+
+	s := t.nonVoters
+	// The report is sent on the last block of a cycle unless it ends the period
+	if level == s.period.EndLevel || !s.isCycleEnd(level) {
+		return nil
+	}
+
+	lastCycle := level == s.period.EndLevel-s.blocksPerCycle
+	if !lastCycle && !t.config.IsNonVotersEveryCycle() {
+		return nil
+	}
+
+	log.Printf("TezosListener: Inspecting block %s for non voters.\n", block.Hash)
+
+	listings, err := getListings(ctx, t.nodes.Service(), t.config.GetChainID(), block.Hash)
+	if err != nil {
+		return err
+	}
+
+	ballotList, err := getBallotList(ctx, t.nodes.Service(), t.config.GetChainID(), block.Hash)
+	if err != nil {
+		return err
+	}
+
+	proposal, err := t.nodes.Service().GetCurrentProposals(ctx, t.config.GetChainID(), block.Hash)
+	if err != nil {
+		return err
+	}
+
+	voted := make(map[string]bool, len(ballotList))
+	for _, entry := range ballotList {
+		voted[entry.PKH] = true
+	}
+
+	report := &models.NonVoterReport{
+		BlockRef:         blockRef(block, ""),
+		Period:           s.period.Index,
+		Phase:            normalizePeriodKind(periodKind),
+		ProposalHash:     proposal,
+		LastCycle:        lastCycle,
+		BlocksRemaining:  s.period.EndLevel - level,
+		TotalVotingPower: listings.Total(),
+		Unit:             listings.Unit(),
+	}
+
+	nonVoters := []models.Voter{}
+	for _, entry := range listings {
+		if voted[entry.PKH] {
+			continue
+		}
+		nonVoters = append(nonVoters, models.Voter{PKH: entry.PKH, VotingPower: entry.Power()})
+		report.NonVoters++
+		report.NonVotersPower += entry.Power()
+	}
+
+	if report.NonVoters == 0 {
+		return nil
+	}
+
+	report.Top = topVoters(nonVoters, t.config.GetNonVotersTop())
+
+	t.nonVoterChan <- report
+	return nil
+}
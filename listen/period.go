@@ -0,0 +1,50 @@
+package listen
+
+// Voting period kinds, newer protocols renamed testing_vote, testing and promotion_vote
+const (
+	periodProposal      = "proposal"
+	periodTestingVote   = "testing_vote"
+	periodExploration   = "exploration"
+	periodTesting       = "testing"
+	periodCooldown      = "cooldown"
+	periodPromotionVote = "promotion_vote"
+	periodPromotion     = "promotion"
+	periodAdoption      = "adoption"
+)
+
+// isProposalPeriod returns true if proposals can be injected during the period kind
+func isProposalPeriod(kind string) bool {
+	return kind == periodProposal
+}
+
+// isBallotPeriod returns true if ballots can be cast during the period kind
+func isBallotPeriod(kind string) bool {
+	switch kind {
+	case periodTestingVote, periodExploration, periodPromotionVote, periodPromotion:
+		return true
+	}
+	return false
+}
+
+// isExplorationPeriod returns true for the first ballot period, named testing_vote by older protocols
+func isExplorationPeriod(kind string) bool {
+	return kind == periodTestingVote || kind == periodExploration
+}
+
+// normalizePeriodKind returns the name newer protocols use for a period kind
+func normalizePeriodKind(kind string) string {
+	switch kind {
+	case periodTestingVote:
+		return periodExploration
+	case periodTesting:
+		return periodCooldown
+	case periodPromotionVote:
+		return periodPromotion
+	}
+	return kind
+}
+
+// isAdoptionPeriod returns true for the period preceding the activation of an approved proposal
+func isAdoptionPeriod(kind string) bool {
+	return kind == periodAdoption
+}
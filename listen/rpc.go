@@ -0,0 +1,369 @@
+package listen
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	tezos "github.com/ecadlabs/go-tezos"
+	"github.com/ecadlabs/tezos-bot/models"
+)
+
+// votingValidationPass is the index of the operation list holding proposals and ballots
+const votingValidationPass = 1
+
+// blockHeader holds a block header as returned by the header rpc
+type blockHeader struct {
+	tezos.RawBlockHeader
+	Protocol string `json:"protocol"`
+	ChainID  string `json:"chain_id"`
+	Hash     string `json:"hash"`
+}
+
+// constants holds the subset of the protocol constants used by the listener
+type constants struct {
+	BlocksPerCycle        int          `json:"blocks_per_cycle"`
+	BlocksPerVotingPeriod int          `json:"blocks_per_voting_period"`
+	CyclesPerVotingPeriod int          `json:"cycles_per_voting_period"`
+	TimeBetweenBlocks     []int64Value `json:"time_between_blocks"`
+	MinimalBlockDelay     *int64Value  `json:"minimal_block_delay"`
+	// QuorumMin and QuorumMax are only present from Babylon onwards, in hundredths of percent
+	QuorumMin int64Value `json:"quorum_min"`
+	QuorumMax int64Value `json:"quorum_max"`
+	// MinProposalQuorum is the share of the voting power a proposal needs to advance, in hundredths of percent
+	MinProposalQuorum int64Value `json:"min_proposal_quorum"`
+}
+
+// BlockDelay returns the expected time between two blocks
+func (c *constants) BlockDelay() time.Duration {
+	if c.MinimalBlockDelay != nil {
+		return time.Duration(*c.MinimalBlockDelay) * time.Second
+	}
+	if len(c.TimeBetweenBlocks) > 0 {
+		return time.Duration(c.TimeBetweenBlocks[0]) * time.Second
+	}
+	return time.Minute
+}
+
+// VotingPeriodLength returns the number of blocks in a voting period
+func (c *constants) VotingPeriodLength() int {
+	if c.BlocksPerVotingPeriod != 0 {
+		return c.BlocksPerVotingPeriod
+	}
+	return c.CyclesPerVotingPeriod * c.BlocksPerCycle
+}
+
+// votingPeriod describes the voting period a block belongs to
+type votingPeriod struct {
+	Index      int
+	Kind       string
+	StartLevel int
+	EndLevel   int
+}
+
+type currentPeriodResponse struct {
+	VotingPeriod struct {
+		Index         int    `json:"index"`
+		Kind          string `json:"kind"`
+		StartPosition int    `json:"start_position"`
+	} `json:"voting_period"`
+	Position  int `json:"position"`
+	Remaining int `json:"remaining"`
+}
+
+type blockMetadataResponse struct {
+	Level struct {
+		Level                int `json:"level"`
+		VotingPeriod         int `json:"voting_period"`
+		VotingPeriodPosition int `json:"voting_period_position"`
+	} `json:"level"`
+	VotingPeriodKind string `json:"voting_period_kind"`
+}
+
+func getRPC(ctx context.Context, service *tezos.Service, path string, v interface{}) error {
+	req, err := service.Client.NewRequest(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return err
+	}
+	return service.Client.Do(req, v)
+}
+
+func blockPath(chainID, blockID string) string {
+	return "/chains/" + chainID + "/blocks/" + blockID
+}
+
+// getBlockHeader returns the header of a block without fetching its operations
+func getBlockHeader(ctx context.Context, service *tezos.Service, chainID, blockID string) (*blockHeader, error) {
+	var header blockHeader
+	if err := getRPC(ctx, service, blockPath(chainID, blockID)+"/header", &header); err != nil {
+		return nil, err
+	}
+	return &header, nil
+}
+
+// getConstants returns the protocol constants at a block
+func getConstants(ctx context.Context, service *tezos.Service, chainID, blockID string) (*constants, error) {
+	var c constants
+	if err := getRPC(ctx, service, blockPath(chainID, blockID)+"/context/constants", &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// getOperationsPass returns the operations of a single validation pass of a block
+func getOperationsPass(ctx context.Context, service *tezos.Service, chainID, blockID string, pass int) ([]*tezos.Operation, error) {
+	var ops []*tezos.Operation
+	if err := getRPC(ctx, service, blockPath(chainID, blockID)+"/operations/"+strconv.Itoa(pass), &ops); err != nil {
+		return nil, err
+	}
+	return ops, nil
+}
+
+// getVotingPeriod returns the voting period of the block at level. Protocols without the current_period
+// rpc are handled using the block metadata and the voting period length
+func getVotingPeriod(ctx context.Context, service *tezos.Service, chainID string, level int) (*votingPeriod, error) {
+	blockID := strconv.Itoa(level)
+
+	var current currentPeriodResponse
+	err := getRPC(ctx, service, blockPath(chainID, blockID)+"/votes/current_period", &current)
+	if err == nil {
+		return &votingPeriod{
+			Index:      current.VotingPeriod.Index,
+			Kind:       current.VotingPeriod.Kind,
+			StartLevel: level - current.Position,
+			EndLevel:   level + current.Remaining,
+		}, nil
+	}
+
+	var metadata blockMetadataResponse
+	if err := getRPC(ctx, service, blockPath(chainID, blockID)+"/metadata", &metadata); err != nil {
+		return nil, err
+	}
+
+	c, err := getConstants(ctx, service, chainID, blockID)
+	if err != nil {
+		return nil, err
+	}
+
+	start := level - metadata.Level.VotingPeriodPosition
+	return &votingPeriod{
+		Index:      metadata.Level.VotingPeriod,
+		Kind:       metadata.VotingPeriodKind,
+		StartLevel: start,
+		EndLevel:   start + c.VotingPeriodLength() - 1,
+	}, nil
+}
+
+// getParticipationEMA returns the participation exponential moving average at a block in hundredths of percent,
+// the rpc exists from Babylon onwards
+func getParticipationEMA(ctx context.Context, service *tezos.Service, chainID, blockID string) (int, error) {
+	var ema int
+	err := getRPC(ctx, service, blockPath(chainID, blockID)+"/votes/participation_ema", &ema)
+	return ema, err
+}
+
+// levelInfo is the position of a block in the chain as returned by the current_level rpc
+type levelInfo struct {
+	Level         int `json:"level"`
+	Cycle         int `json:"cycle"`
+	CyclePosition int `json:"cycle_position"`
+}
+
+// getCycle returns the cycle of the chain a block belongs to, cycles changed length across protocols
+func getCycle(ctx context.Context, service *tezos.Service, chainID, blockID string) (int, error) {
+	var info levelInfo
+	if err := getRPC(ctx, service, blockPath(chainID, blockID)+"/helpers/current_level", &info); err != nil {
+		return 0, err
+	}
+	return info.Cycle, nil
+}
+
+// getPeriodKind returns the voting period kind at a block, using the current_period rpc on protocols
+// which removed current_period_kind
+func getPeriodKind(ctx context.Context, service *tezos.Service, chainID, blockID string) (string, error) {
+	var kind string
+	err := getRPC(ctx, service, blockPath(chainID, blockID)+"/votes/current_period_kind", &kind)
+	if err == nil {
+		return kind, nil
+	}
+
+	var current currentPeriodResponse
+	if err := getRPC(ctx, service, blockPath(chainID, blockID)+"/votes/current_period", &current); err != nil {
+		return "", err
+	}
+	return current.VotingPeriod.Kind, nil
+}
+
+// getVotingBlock returns a block holding only its header and its voting operations
+func getVotingBlock(ctx context.Context, service *tezos.Service, chainID string, level int, period *votingPeriod, ops []*tezos.Operation) (*tezos.Block, error) {
+	header, err := getBlockHeader(ctx, service, chainID, strconv.Itoa(level))
+	if err != nil {
+		return nil, err
+	}
+
+	block := &tezos.Block{
+		Protocol:   header.Protocol,
+		ChainID:    header.ChainID,
+		Hash:       header.Hash,
+		Header:     header.RawBlockHeader,
+		Operations: make([][]*tezos.Operation, votingValidationPass+1),
+	}
+	block.Operations[votingValidationPass] = ops
+	block.Metadata.Protocol = header.Protocol
+	block.Metadata.VotingPeriodKind = period.Kind
+	block.Metadata.Level.Level = level
+	block.Metadata.Level.VotingPeriod = period.Index
+	block.Metadata.Level.VotingPeriodPosition = level - period.StartLevel
+
+	return block, nil
+}
+
+// int64Value decodes an integer encoded either as a JSON number or as a string, newer protocols encode int64 values as strings
+type int64Value int64
+
+// UnmarshalJSON implements json.Unmarshaler
+func (i *int64Value) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), "\"")
+	if s == "null" {
+		*i = 0
+		return nil
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return err
+	}
+	*i = int64Value(v)
+	return nil
+}
+
+// listing holds a delegate voting power, in rolls on older protocols and in mutez on newer ones
+type listing struct {
+	PKH         string      `json:"pkh"`
+	Rolls       *int64Value `json:"rolls"`
+	VotingPower *int64Value `json:"voting_power"`
+}
+
+// Power returns the voting power of the delegate in the unit of the listing
+func (l *listing) Power() int64 {
+	if l.VotingPower != nil {
+		return int64(*l.VotingPower)
+	}
+	if l.Rolls != nil {
+		return int64(*l.Rolls)
+	}
+	return 0
+}
+
+// Unit returns the unit of the voting power
+func (l *listing) Unit() models.VotingPowerUnit {
+	if l.VotingPower != nil {
+		return models.Mutez
+	}
+	return models.Rolls
+}
+
+// listings is the voting listings of a block
+type listings []*listing
+
+// Unit returns the unit of the voting power
+func (l listings) Unit() models.VotingPowerUnit {
+	if len(l) > 0 {
+		return l[0].Unit()
+	}
+	return models.Rolls
+}
+
+// Total returns the total voting power of the listings
+func (l listings) Total() int64 {
+	total := int64(0)
+	for _, entry := range l {
+		total += entry.Power()
+	}
+	return total
+}
+
+// PowerOf returns the voting power of a delegate, 0 if it is not listed
+func (l listings) PowerOf(pkh string) int64 {
+	for _, entry := range l {
+		if entry.PKH == pkh {
+			return entry.Power()
+		}
+	}
+	return 0
+}
+
+// ballots holds the ballot tallies of a voting period, in the same unit as the listings
+type ballots struct {
+	Yay  int64Value `json:"yay"`
+	Nay  int64Value `json:"nay"`
+	Pass int64Value `json:"pass"`
+}
+
+// ballotEntry is the ballot cast by a delegate
+type ballotEntry struct {
+	PKH    string `json:"pkh"`
+	Ballot string `json:"ballot"`
+}
+
+// proposal holds a proposal and the voting power of its upvotes
+type proposal struct {
+	ProposalHash string
+	Power        int64
+}
+
+// getListings returns the voting listings at a block
+func getListings(ctx context.Context, service *tezos.Service, chainID, blockID string) (listings, error) {
+	var l listings
+	if err := getRPC(ctx, service, blockPath(chainID, blockID)+"/votes/listings", &l); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// getBallots returns the ballot tallies at a block
+func getBallots(ctx context.Context, service *tezos.Service, chainID, blockID string) (*ballots, error) {
+	var b ballots
+	if err := getRPC(ctx, service, blockPath(chainID, blockID)+"/votes/ballots", &b); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// getBallotList returns the ballots cast by each delegate at a block
+func getBallotList(ctx context.Context, service *tezos.Service, chainID, blockID string) ([]*ballotEntry, error) {
+	var l []*ballotEntry
+	if err := getRPC(ctx, service, blockPath(chainID, blockID)+"/votes/ballot_list", &l); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// getProposals returns the proposals of the current proposal period with their upvotes
+func getProposals(ctx context.Context, service *tezos.Service, chainID, blockID string) ([]*proposal, error) {
+	var resp [][]json.RawMessage
+	if err := getRPC(ctx, service, blockPath(chainID, blockID)+"/votes/proposals", &resp); err != nil {
+		return nil, err
+	}
+
+	proposals := make([]*proposal, len(resp))
+	for i, entry := range resp {
+		if len(entry) != 2 {
+			return nil, fmt.Errorf("Malformed request Proposal is expected to be tuple of size 2")
+		}
+		p := &proposal{}
+		if err := json.Unmarshal(entry[0], &p.ProposalHash); err != nil {
+			return nil, err
+		}
+		var power int64Value
+		if err := json.Unmarshal(entry[1], &power); err != nil {
+			return nil, err
+		}
+		p.Power = int64(power)
+		proposals[i] = p
+	}
+	return proposals, nil
+}
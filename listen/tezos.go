@@ -3,9 +3,10 @@ package listen
 import (
 	"context"
 	"log"
-	"net/http"
+	"time"
 
 	"github.com/ecadlabs/go-tezos"
+	"github.com/ecadlabs/tezos-bot/health"
 	"github.com/ecadlabs/tezos-bot/models"
 )
 
@@ -13,99 +14,202 @@ var lastBlock *tezos.Block
 
 // TezosConfig interface with method necessary to obtain tezos listener configurable parameter
 type TezosConfig interface {
-	GetRPCURL() string
+	GetRPCURLs() []string
+	GetMaxHeadLag() int
+	IsCrossCheckHead() bool
+	GetNodeCheckInterval() time.Duration
 	GetChainID() string
 	GetRetryCount() int
+	GetRetryMaxInterval() time.Duration
 	IsMonitorVote() bool
 	IsMonitorProtocol() bool
 	IsMonitorProposal() bool
+	IsMonitorPeriod() bool
+	IsMonitorReport() bool
+	GetBallotDigestInterval() time.Duration
+	IsBallotDigestCycle() bool
+	GetBallotDigestThreshold() float64
+	IsMonitorMilestone() bool
+	GetParticipationMilestones() []float64
+	IsMonitorNonVoters() bool
+	IsNonVotersEveryCycle() bool
+	GetNonVotersTop() int
+	IsMonitorLeaderboard() bool
+	IsMonitorHead() bool
+	GetSupermajority() float64
+	GetActivationCountdown() []time.Duration
 	IsHistory() bool
 	GetHistoryStartingBlock() int
+	GetHistoryEndingBlock() int
+	GetHistoryWorkers() int
+	GetHistoryRate() float64
+}
+
+// StatusReporter interface with method necessary to report the listener state to health checks
+type StatusReporter interface {
+	SetMode(mode string)
+	SetHead(level int, hash string)
+	SetNodeError(err error)
+	SetStreamState(state string)
+	StreamReconnected()
+	SetNode(url string, level int, active bool, err error)
+	SetBackfillProgress(start, end, level int)
+}
+
+// StateStore interface with method necessary to persist the listener state across restarts
+type StateStore interface {
+	Load(key string, v interface{}) (bool, error)
+	Save(key string, v interface{}) error
 }
 
 // TezosListener is a struct containing information necessary to monitor the tezos chain
 type TezosListener struct {
-	service             *tezos.Service
+	nodes               *NodePool
 	votesChan           chan *models.Ballot
-	protoChan           chan string
+	protoChan           chan *models.ProtocolChange
 	newProposalChan     chan *models.Proposal
 	proposalUpvoteChan  chan *models.Proposal
 	proposalSummaryChan chan *models.ProposalSummary
 	winningProposalChan chan *models.ProposalSummary
-	cache               *cache
-	signals             chan bool
-	config              TezosConfig
-	bStreaming          BlockStreamingFunc
+	periodChan          chan *models.VotingPeriod
+	countdownChan       chan *models.ActivationCountdown
+	transitionChan      chan *models.PeriodTransition
+	finalReportChan     chan *models.FinalReport
+	ballotDigestChan    chan *models.BallotDigest
+	milestoneChan       chan *models.Milestone
+	nonVoterChan        chan *models.NonVoterReport
+	leaderboardChan     chan *models.ProposalLeaderboard
+	headChan            chan *models.Head
+	period              *periodState
+	report              *reportState
+	digest              *digestState
+	digestLoaded        bool
+	milestones          *milestoneState
+	nonVoters           *cycleState
+	leaderboard         *leaderboardState
+	// proposalCycles is the voting period of the proposal summaries and winning proposals
+	proposalCycles *cycleState
+	// head is the voting period of the emitted heads when period changes are not monitored
+	head       *periodState
+	cache      *cache
+	ctx        context.Context
+	cancel     context.CancelFunc
+	config     TezosConfig
+	bStreaming BlockStreamingFunc
+	status     StatusReporter
+	store      StateStore
 }
 
 // NewTezosListener create a new TezosListener
-func NewTezosListener(config TezosConfig) (*TezosListener, error) {
-	client, err := tezos.NewRPCClient(http.DefaultClient, config.GetRPCURL())
+func NewTezosListener(config TezosConfig, status StatusReporter, store StateStore) (*TezosListener, error) {
+	nodes, err := NewNodePool(config.GetRPCURLs(), config.GetMaxHeadLag(), status)
 	if err != nil {
 		return nil, err
 	}
 
 	var bStreamingFunc BlockStreamingFunc = MonitorBlockStreamingFunc
+	mode := health.ModeLive
 
 	if config.IsHistory() {
 		bStreamingFunc = HistoryBlockStreamingFunc
+		mode = health.ModeHistory
 	}
 
+	status.SetMode(mode)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
 	return &TezosListener{
-		service:             &tezos.Service{Client: client},
+		nodes:               nodes,
 		cache:               newCache(),
 		votesChan:           make(chan *models.Ballot),
-		protoChan:           make(chan string),
+		protoChan:           make(chan *models.ProtocolChange),
 		newProposalChan:     make(chan *models.Proposal),
 		proposalUpvoteChan:  make(chan *models.Proposal),
 		proposalSummaryChan: make(chan *models.ProposalSummary),
 		winningProposalChan: make(chan *models.ProposalSummary),
-		signals:             make(chan bool),
+		periodChan:          make(chan *models.VotingPeriod),
+		countdownChan:       make(chan *models.ActivationCountdown),
+		transitionChan:      make(chan *models.PeriodTransition),
+		finalReportChan:     make(chan *models.FinalReport),
+		ballotDigestChan:    make(chan *models.BallotDigest),
+		milestoneChan:       make(chan *models.Milestone),
+		nonVoterChan:        make(chan *models.NonVoterReport),
+		leaderboardChan:     make(chan *models.ProposalLeaderboard),
+		headChan:            make(chan *models.Head),
+		ctx:                 ctx,
+		cancel:              cancel,
 		config:              config,
 		bStreaming:          bStreamingFunc,
+		status:              status,
+		store:               store,
 	}, nil
 }
 
-// Start start monitoring the chain and push new Ballot in the ballot channel
-func (t *TezosListener) Start() {
-	ctx := context.Background()
-	cBlockHash := make(chan string)
-	defer close(cBlockHash)
+// Start start monitoring the chain and push new Ballot in the ballot channel.
+// It blocks until the listener is stopped or the block stream gives up, in which case the error is returned
+func (t *TezosListener) Start() error {
+	ctx := t.ctx
+	cBlock := make(chan *tezos.Block)
+	errc := make(chan error, 1)
 	go func() {
-		for {
-			select {
-			case _ = <-t.signals:
-				return
-			default:
-				err := t.bStreaming(ctx, t.config, t.service, cBlockHash)
-				if err != nil {
-					panic(err.Error())
-				}
-				return
-			}
-		}
+		defer close(cBlock)
+		errc <- t.bStreaming(ctx, t.config, t.nodes, t.status, cBlock)
 	}()
+	go t.checkNodes(ctx)
 
-	for hash := range cBlockHash {
-		// cBlockHash channel can emit the same has multiple time
+	for block := range cBlock {
+		hash := block.Hash
+		// cBlock channel can emit the same block multiple time
 		// In order to avoid duplicate we check if it has already been processed
 		if !t.cache.Has(hash) {
 			t.cache.Add(hash)
-			block, err := t.service.GetBlock(ctx, t.config.GetChainID(), hash)
 
-			if err != nil {
-				log.Printf("Block: %s skipped because of error: %s\n", hash, err.Error())
-				continue
+			if t.config.IsCrossCheckHead() {
+				if err := t.nodes.CrossCheck(ctx, t.config.GetChainID(), block.Header.Level, block.Hash); err != nil {
+					log.Printf("Block: %s skipped because of error: %s\n", hash, err.Error())
+					continue
+				}
 			}
 
-			periodKind, err := t.service.GetCurrentPeriodKind(ctx, t.config.GetChainID(), block.Hash)
+			t.status.SetHead(block.Header.Level, block.Hash)
+
+			periodKind, err := getPeriodKind(ctx, t.nodes.Service(), t.config.GetChainID(), block.Hash)
 
 			if err != nil {
 				log.Printf("Block: %s skipped because of error: %s\n", hash, err.Error())
 				continue
 			}
 
-			if t.config.IsMonitorVote() && (periodKind.IsTestingVote() || periodKind.IsPromotionVote()) {
+			if t.config.IsMonitorPeriod() {
+				err = t.lookForPeriodChange(ctx, block)
+				if err != nil {
+					log.Printf("Block: %s skipped because of error: %s\n", hash, err.Error())
+					continue
+				}
+			}
+
+			if t.config.IsMonitorHead() {
+				err = t.lookForHead(ctx, block)
+				if err != nil {
+					log.Printf("Block: %s skipped because of error: %s\n", hash, err.Error())
+					continue
+				}
+			}
+
+			if t.config.IsMonitorReport() {
+				err = t.lookForFinalReport(ctx, block)
+				if err != nil {
+					log.Printf("Block: %s skipped because of error: %s\n", hash, err.Error())
+					continue
+				}
+			}
+
+			if t.config.IsMonitorVote() {
+				t.lookForBallotDigest(block)
+			}
+
+			if t.config.IsMonitorVote() && isBallotPeriod(periodKind) {
 				err = t.lookForBallot(ctx, block, periodKind)
 				if err != nil {
 					log.Printf("Block: %s skipped because of error: %s\n", hash, err.Error())
@@ -113,6 +217,22 @@ func (t *TezosListener) Start() {
 				}
 			}
 
+			if t.config.IsMonitorMilestone() && isBallotPeriod(periodKind) {
+				err = t.lookForMilestones(ctx, block, periodKind)
+				if err != nil {
+					log.Printf("Block: %s skipped because of error: %s\n", hash, err.Error())
+					continue
+				}
+			}
+
+			if t.config.IsMonitorNonVoters() && isBallotPeriod(periodKind) {
+				err = t.lookForNonVoters(ctx, block, periodKind)
+				if err != nil {
+					log.Printf("Block: %s skipped because of error: %s\n", hash, err.Error())
+					continue
+				}
+			}
+
 			if t.config.IsMonitorProtocol() {
 				err = t.lookForProtocolChange(ctx, block)
 				if err != nil {
@@ -121,7 +241,7 @@ func (t *TezosListener) Start() {
 				}
 			}
 
-			if t.config.IsMonitorProposal() && periodKind.IsProposal() {
+			if t.config.IsMonitorProposal() && isProposalPeriod(periodKind) {
 				err = t.lookForProposal(ctx, block)
 				if err != nil {
 					log.Printf("Block: %s skipped because of error: %s\n", hash, err.Error())
@@ -129,7 +249,7 @@ func (t *TezosListener) Start() {
 				}
 			}
 
-			if t.config.IsMonitorProposal() && (periodKind.IsProposal()) {
+			if t.config.IsMonitorProposal() && isProposalPeriod(periodKind) {
 				err = t.lookForProposalSummary(ctx, block)
 				if err != nil {
 					log.Printf("Block: %s skipped because of error: %s\n", hash, err.Error())
@@ -137,7 +257,15 @@ func (t *TezosListener) Start() {
 				}
 			}
 
-			if t.config.IsMonitorProposal() && (periodKind.IsTestingVote()) {
+			if t.config.IsMonitorLeaderboard() && isProposalPeriod(periodKind) {
+				err = t.lookForProposalLeaderboard(ctx, block)
+				if err != nil {
+					log.Printf("Block: %s skipped because of error: %s\n", hash, err.Error())
+					continue
+				}
+			}
+
+			if t.config.IsMonitorProposal() && isExplorationPeriod(periodKind) {
 				err = t.lookForWinningProposal(ctx, block)
 				if err != nil {
 					log.Printf("Block: %s skipped because of error: %s\n", hash, err.Error())
@@ -146,11 +274,48 @@ func (t *TezosListener) Start() {
 			}
 		}
 	}
+
+	err := <-errc
+	// A backfill which went through its ending block publishes the ballots of its last window. When the listener
+	// is stopped or the stream fails the pending window is left in the store and resumes after a restart
+	if t.config.IsHistory() && err == nil && ctx.Err() == nil {
+		t.flushBallotDigest()
+	}
+	return err
+}
+
+// checkNodes periodically refreshes the health of the rpc nodes until ctx is done
+func (t *TezosListener) checkNodes(ctx context.Context) {
+	interval := t.config.GetNodeCheckInterval()
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			t.nodes.Refresh(ctx, t.config.GetChainID())
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// blockRef returns the reference of an event detected in block, and in the given operation if any
+func blockRef(block *tezos.Block, operationHash string) models.BlockRef {
+	return models.BlockRef{
+		Level:         block.Header.Level,
+		BlockHash:     block.Hash,
+		OperationHash: operationHash,
+		Timestamp:     block.Header.Timestamp,
+	}
 }
 
 // Stop stop the tezos listener
 func (t *TezosListener) Stop() {
-	t.signals <- true
+	t.cancel()
 }
 
 // GetNewVotes returns a Ballot channel
@@ -158,8 +323,8 @@ func (t *TezosListener) GetNewVotes() chan *models.Ballot {
 	return t.votesChan
 }
 
-// GetNewProto returns a proto channel
-func (t *TezosListener) GetNewProto() chan string {
+// GetNewProto returns a protocol change channel
+func (t *TezosListener) GetNewProto() chan *models.ProtocolChange {
 	return t.protoChan
 }
 
@@ -182,3 +347,48 @@ func (t *TezosListener) GetProposalSummary() chan *models.ProposalSummary {
 func (t *TezosListener) GetWinningProposal() chan *models.ProposalSummary {
 	return t.winningProposalChan
 }
+
+// GetNewPeriod returns a voting period channel
+func (t *TezosListener) GetNewPeriod() chan *models.VotingPeriod {
+	return t.periodChan
+}
+
+// GetActivationCountdown returns an activation countdown channel
+func (t *TezosListener) GetActivationCountdown() chan *models.ActivationCountdown {
+	return t.countdownChan
+}
+
+// GetPeriodTransition returns a period transition channel
+func (t *TezosListener) GetPeriodTransition() chan *models.PeriodTransition {
+	return t.transitionChan
+}
+
+// GetFinalReport returns a ballot period final report channel
+func (t *TezosListener) GetFinalReport() chan *models.FinalReport {
+	return t.finalReportChan
+}
+
+// GetBallotDigest returns a ballot digest channel
+func (t *TezosListener) GetBallotDigest() chan *models.BallotDigest {
+	return t.ballotDigestChan
+}
+
+// GetMilestone returns a vote milestone channel
+func (t *TezosListener) GetMilestone() chan *models.Milestone {
+	return t.milestoneChan
+}
+
+// GetNonVoterReport returns a non voter report channel
+func (t *TezosListener) GetNonVoterReport() chan *models.NonVoterReport {
+	return t.nonVoterChan
+}
+
+// GetProposalLeaderboard returns a proposal leaderboard channel
+func (t *TezosListener) GetProposalLeaderboard() chan *models.ProposalLeaderboard {
+	return t.leaderboardChan
+}
+
+// GetNewHead returns a channel of every processed block with its voting period
+func (t *TezosListener) GetNewHead() chan *models.Head {
+	return t.headChan
+}
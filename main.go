@@ -2,56 +2,119 @@ package main
 
 import (
 	"flag"
-	"log"
+	"fmt"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/ecadlabs/tezos-bot/config"
-	"github.com/ecadlabs/tezos-bot/listen"
-	"github.com/ecadlabs/tezos-bot/publish"
-	"github.com/ecadlabs/tezos-bot/service"
 )
 
-func main() {
-
-	c := config.Config{
-		RPCURL:               "https://mainnet-node.tzscan.io",
-		ChainID:              "main",
-		RetryCount:           100,
-		History:              false,
-		MonitorVote:          true,
-		MonitorProtocol:      true,
-		MonitorProposal:      true,
-		HistoryStartingBlock: 0,
+// defaultConfig returns the configuration used for the parameters missing from the config file
+func defaultConfig() config.Config {
+	return config.Config{
+		RPCURLs:                 []string{"https://mainnet.api.tez.ie", "https://rpc.tzbeta.net"},
+		MaxHeadLag:              3,
+		NodeCheckInterval:       30 * time.Second,
+		ChainID:                 "main",
+		RetryCount:              0,
+		RetryMaxInterval:        time.Minute,
+		History:                 false,
+		MonitorVote:             true,
+		MonitorProtocol:         true,
+		MonitorProposal:         true,
+		MonitorPeriod:           false,
+		MonitorReport:           false,
+		MonitorMilestone:        false,
+		ParticipationMilestones: []float64{25, 50, 75},
+		StateFile:               "./state.json",
+		MonitorNonVoters:        false,
+		NonVotersTop:            5,
+		MonitorLeaderboard:      false,
+		EventStore:              "",
+		ActivationCountdown:     []time.Duration{24 * time.Hour, time.Hour, 10 * time.Minute},
+		HistoryStartingBlock:    0,
+		HistoryWorkers:          4,
+		HistoryRate:             10,
+		HealthAddr:              ":8080",
+		APIAddr:                 "",
+		StreamBuffer:            1000,
+		StreamFile:              "./stream.log",
+		ReadinessTimeout:        5 * time.Minute,
 	}
+}
 
-	var configFile string
-	flag.StringVar(&configFile, "c", "./config.yaml", "Config file.")
-	flag.Parse()
-	c.Load(configFile)
+// command is a subcommand of the bot
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) int
+}
 
-	l, err := listen.NewTezosListener(c)
+var commands = []command{
+	{"run", "monitor the chain and publish the detected events (default)", runRun},
+	{"backfill", "replay a range of levels and publish the detected events", runBackfill},
+	{"replay", "re-run the detectors over a range of levels, printing the events instead of publishing them", runReplay},
+	{"preview", "render the messages of recorded events for each publisher", runPreview},
+	{"announce", "post a message on demand through the publishers", runAnnounce},
+	{"export", "export ballots, proposal upvotes or period outcomes as CSV or JSON Lines", runExport},
+	{"verify-config", "check the config file", runVerifyConfig},
+	{"status", "show the health report of a running bot", runStatus},
+}
 
-	if err != nil {
-		log.Printf(err.Error())
-		return
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: tezos-bot [command] [flags]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-14s %s\n", cmd.name, cmd.summary)
 	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Run tezos-bot <command> -h for the flags of a command.")
+}
 
-	var p service.VotePublisher
-
-	if c.GetTwitterAccessToken() == "" {
-		log.Printf("Twitter access token not configured posting vote to stdout\n")
-		p = &publish.DebugPublisher{}
-	} else {
-		p, err = publish.NewTwitterPublisher(c)
+func main() {
+	// Without a command the bot runs, as it did before subcommands existed
+	name, args := "run", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
 
-		if err != nil {
-			log.Printf(err.Error())
-			return
+	for _, cmd := range commands {
+		if cmd.name == name {
+			os.Exit(cmd.run(args))
 		}
 	}
 
-	s := service.New(l, p)
+	if name == "help" {
+		usage()
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", name)
+	usage()
+	os.Exit(2)
+}
 
-	log.Println("Bot started...")
+// newFlagSet returns the flag set of a command, synopsis follows the command name in the usage
+func newFlagSet(name, synopsis, description string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: tezos-bot %s %s\n\n%s\n\nFlags:\n", name, synopsis, description)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// configFlag registers the config file flag shared by all commands
+func configFlag(fs *flag.FlagSet) *string {
+	return fs.String("c", "./config.yaml", "Config file.")
+}
 
-	s.Start()
+// loadConfig returns the default configuration overridden by the config file, which is optional
+func loadConfig(name string) (config.Config, error) {
+	c := defaultConfig()
+	if err := c.Load(name); err != nil && !os.IsNotExist(err) {
+		return c, fmt.Errorf("unable to load config %s: %s", name, err.Error())
+	}
+	return c, nil
 }
@@ -0,0 +1,14 @@
+package models
+
+// Announcement is a message posted on demand rather than detected on chain
+type Announcement struct {
+	BlockRef
+
+	// Template is the name of the announcement template the message is rendered from, empty to post Text as is
+	Template string
+	// Vars are the variables of the template
+	Vars map[string]string
+	Text string
+	// Schedule is the name of the scheduled announcement that posted the message, empty when posted by hand
+	Schedule string
+}
@@ -0,0 +1,45 @@
+package models
+
+import "sort"
+
+// BallotDigest aggregates the ballots cast during a digest window
+type BallotDigest struct {
+	BlockRef
+
+	ProposalHash string
+	Period       int
+	IsTesting    bool
+	// Ballots are in the order they were cast, the last one holds the latest tallies
+	Ballots []*Ballot
+}
+
+// Latest returns the last ballot of the window, it holds the updated tallies
+func (d *BallotDigest) Latest() *Ballot {
+	if len(d.Ballots) == 0 {
+		return nil
+	}
+	return d.Ballots[len(d.Ballots)-1]
+}
+
+// Count returns the number of ballots of the given kind (yay, nay or pass) cast during the window
+func (d *BallotDigest) Count(ballot string) int {
+	count := 0
+	for _, b := range d.Ballots {
+		if b.Ballot == ballot {
+			count++
+		}
+	}
+	return count
+}
+
+// TopVoters returns the n ballots with the most voting power
+func (d *BallotDigest) TopVoters(n int) []*Ballot {
+	top := append([]*Ballot{}, d.Ballots...)
+	sort.SliceStable(top, func(i, j int) bool {
+		return top[i].VotingPower > top[j].VotingPower
+	})
+	if len(top) > n {
+		top = top[:n]
+	}
+	return top
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Event kinds, as recorded in the event store
+const (
+	EventBallot              = "ballot"
+	EventProtocol            = "protocol"
+	EventProposalInjection   = "proposal_injection"
+	EventProposalUpvote      = "proposal_upvote"
+	EventProposalSummary     = "proposal_summary"
+	EventWinningProposal     = "winning_proposal"
+	EventVotingPeriod        = "voting_period"
+	EventActivationCountdown = "activation_countdown"
+	EventPeriodTransition    = "period_transition"
+	EventFinalReport         = "final_report"
+	EventBallotDigest        = "ballot_digest"
+	EventMilestone           = "milestone"
+	EventNonVoterReport      = "non_voter_report"
+	EventProposalLeaderboard = "proposal_leaderboard"
+	EventAnnouncement        = "announcement"
+)
+
+// BlockRef locates the block, and the operation if any, an event was detected in
+type BlockRef struct {
+	Level         int
+	BlockHash     string
+	OperationHash string
+	Timestamp     time.Time
+}
@@ -0,0 +1,8 @@
+package models
+
+// Head is a block of the chain with the voting period it belongs to, emitted for every block to drive scheduled announcements
+type Head struct {
+	BlockRef
+
+	Period *VotingPeriod
+}
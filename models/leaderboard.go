@@ -0,0 +1,35 @@
+package models
+
+// LeaderboardEntry is the standing of a proposal in a proposal period
+type LeaderboardEntry struct {
+	ProposalHash string
+	// VotingPower is the voting power of all the upvotes received by the proposal
+	VotingPower int64
+	// Delta is the voting power received since the previous leaderboard
+	Delta      int64
+	Supporters int
+	// AboveQuorum is true if the proposal can advance to the next period
+	AboveQuorum bool
+}
+
+// ProposalLeaderboard ranks all the proposals of a proposal period
+type ProposalLeaderboard struct {
+	BlockRef
+
+	Period int
+	// Cycle is the position of the cycle within the period, starting at 0
+	Cycle int
+	// Entries are sorted by decreasing voting power
+	Entries          []LeaderboardEntry
+	TotalVotingPower int64
+	Unit             VotingPowerUnit
+	// MinProposalQuorum is the share of the voting power in percent a proposal needs to advance, 0 when the protocol has none
+	MinProposalQuorum float64
+	// SupportersComplete is false when the listener did not see the whole period and supporter counts are partial
+	SupportersComplete bool
+}
+
+// PercentOfTotal returns the upvotes of an entry as a percentage of the total voting power
+func (l *ProposalLeaderboard) PercentOfTotal(entry LeaderboardEntry) float64 {
+	return PercentOf(float64(entry.VotingPower), float64(l.TotalVotingPower))
+}
@@ -0,0 +1,22 @@
+package models
+
+// Milestone kinds
+const (
+	MilestoneQuorum            = "quorum"
+	MilestoneSupermajority     = "supermajority"
+	MilestoneSupermajorityLost = "supermajority_lost"
+	MilestoneParticipation     = "participation"
+)
+
+// Milestone is emitted once per ballot period when the tallies cross a threshold
+type Milestone struct {
+	BlockRef
+
+	Kind         string
+	Period       int
+	Phase        string
+	ProposalHash string
+	// Threshold is the participation in percent crossed by participation milestones
+	Threshold float64
+	Result    *BallotResult
+}
@@ -0,0 +1,24 @@
+package models
+
+// NonVoterReport lists the delegates who did not vote yet during a ballot period
+type NonVoterReport struct {
+	BlockRef
+
+	Period       int
+	Phase        string
+	ProposalHash string
+	// LastCycle is true when the report is the reminder sent before the last cycle of the period
+	LastCycle        bool
+	BlocksRemaining  int
+	NonVoters        int
+	NonVotersPower   int64
+	TotalVotingPower int64
+	Unit             VotingPowerUnit
+	// Top are the largest non voters by decreasing voting power
+	Top []Voter
+}
+
+// PercentNonVoters returns the voting power of the delegates who did not vote as a percentage of the total voting power
+func (r *NonVoterReport) PercentNonVoters() float64 {
+	return PercentOf(float64(r.NonVotersPower), float64(r.TotalVotingPower))
+}
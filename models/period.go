@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// VotingPeriod is emitted when the chain enters a new voting period
+type VotingPeriod struct {
+	BlockRef
+
+	Index int
+	// Kind is one of proposal, exploration, cooldown, promotion or adoption
+	Kind         string
+	StartLevel   int
+	EndLevel     int
+	EstimatedEnd time.Time
+	// ProposalHash is the proposal under evaluation, empty during proposal periods
+	ProposalHash string
+}
+
+// ActivationLevel returns the level at which the proposal is activated when the period is an adoption period
+func (p *VotingPeriod) ActivationLevel() int {
+	return p.EndLevel + 1
+}
+
+// ActivationCountdown is emitted during the adoption period as the activation of the approved proposal gets close
+type ActivationCountdown struct {
+	BlockRef
+
+	ProposalHash        string
+	ActivationLevel     int
+	BlocksRemaining     int
+	TimeRemaining       time.Duration
+	EstimatedActivation time.Time
+}
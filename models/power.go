@@ -0,0 +1,30 @@
+package models
+
+// VotingPowerUnit is the unit in which the voting power of a delegate is expressed
+type VotingPowerUnit int
+
+const (
+	// Rolls is used by protocols with roll based listings
+	Rolls VotingPowerUnit = iota
+	// Mutez is used by protocols reporting the voting power as stake
+	Mutez
+)
+
+// MutezPerTez is the number of mutez in a tez
+const MutezPerTez = 1000000
+
+// PercentOf returns power as a percentage of total
+func PercentOf(power, total float64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return (power / total) * 100
+}
+
+// String returns the name of the unit
+func (u VotingPowerUnit) String() string {
+	if u == Mutez {
+		return "mutez"
+	}
+	return "rolls"
+}
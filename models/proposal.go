@@ -1,16 +1,34 @@
 package models
 
-import tezos "github.com/ecadlabs/go-tezos"
-
 type ProposalSummary struct {
-	tezos.Proposal
-	Cycle         int
-	NewSupporters int
+	BlockRef
+
+	ProposalHash string
+	// VotingPower is the voting power of all the upvotes received by the proposal
+	VotingPower      int64
+	NewVotingPower   int64
+	TotalVotingPower int64
+	Unit             VotingPowerUnit
+	Cycle            int
+}
+
+// PercentOfTotal returns the upvotes as a percentage of the total voting power
+func (p *ProposalSummary) PercentOfTotal() float64 {
+	return PercentOf(float64(p.VotingPower), float64(p.TotalVotingPower))
 }
 
 type Proposal struct {
-	ProposalHash string
-	PKH          string
-	Period       int
-	Rolls        int64
+	BlockRef
+
+	ProposalHash     string
+	PKH              string
+	Period           int
+	VotingPower      int64
+	TotalVotingPower int64
+	Unit             VotingPowerUnit
+}
+
+// PercentOfTotal returns the voting power of the baker as a percentage of the total voting power
+func (p *Proposal) PercentOfTotal() float64 {
+	return PercentOf(float64(p.VotingPower), float64(p.TotalVotingPower))
 }
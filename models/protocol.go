@@ -0,0 +1,8 @@
+package models
+
+// ProtocolChange is emitted on the first block of a new protocol
+type ProtocolChange struct {
+	BlockRef
+
+	Protocol string
+}
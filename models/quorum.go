@@ -0,0 +1,86 @@
+package models
+
+import "math/big"
+
+// QuorumModel identifies how a protocol updates the quorum between voting periods
+type QuorumModel int
+
+const (
+	// QuorumLegacy is used by protocols before Babylon, the quorum itself moves towards the participation
+	QuorumLegacy QuorumModel = iota
+	// QuorumEMA is used from Babylon onwards, the quorum is derived from an exponential moving average
+	// of the participation bounded by a minimum and a maximum quorum
+	QuorumEMA
+)
+
+// quorumDenominator is the unit of the quorum related values, they are in hundredths of percent
+const quorumDenominator = 10000
+
+// Quorum holds the quorum of a voting period and what is needed to project the next one.
+// Values are in hundredths of percent as returned by the node, 8000 being 80%
+type Quorum struct {
+	Model   QuorumModel
+	Current int64
+	// EMA is the participation exponential moving average, only used by QuorumEMA
+	EMA int64
+	// Min and Max bound the quorum, only used by QuorumEMA
+	Min int64
+	Max int64
+}
+
+// NewQuorum returns the quorum of a period from the values returned by the node: the current quorum, the
+// participation EMA and the protocol bounds. Protocols without bounds use the legacy model and have no EMA
+func NewQuorum(current, ema, min, max int64) Quorum {
+	if max <= min {
+		return Quorum{
+			Model:   QuorumLegacy,
+			Current: current,
+		}
+	}
+
+	return Quorum{
+		Model:   QuorumEMA,
+		Current: current,
+		EMA:     ema,
+		Min:     min,
+		Max:     max,
+	}
+}
+
+// Percent returns the current quorum in percent
+func (q Quorum) Percent() float64 {
+	return float64(q.Current) / 100
+}
+
+// EMAPercent returns the participation moving average in percent, 0 for the legacy model
+func (q Quorum) EMAPercent() float64 {
+	return float64(q.EMA) / 100
+}
+
+// NextEMA returns the participation moving average of the next period given the participation of this one
+func (q Quorum) NextEMA(participation int64) int64 {
+	return (8000*q.EMA + 2000*participation) / quorumDenominator
+}
+
+// Next returns the quorum of the next period given the participation of this one, in hundredths of percent
+func (q Quorum) Next(participation int64) int64 {
+	if q.Model == QuorumLegacy {
+		return (8000*q.Current + 2000*participation) / quorumDenominator
+	}
+	return q.Min + q.NextEMA(participation)*(q.Max-q.Min)/quorumDenominator
+}
+
+// NextPercent returns the quorum of the next period in percent given the participation of this one in hundredths of percent
+func (q Quorum) NextPercent(participation int64) float64 {
+	return float64(q.Next(participation)) / 100
+}
+
+// Participation returns the share of the total voting power which cast a ballot in hundredths of percent,
+// computed on integers and truncated like the protocol does
+func Participation(votes, total int64) int64 {
+	if total <= 0 {
+		return 0
+	}
+	p := new(big.Int).Mul(big.NewInt(votes), big.NewInt(quorumDenominator))
+	return p.Quo(p, big.NewInt(total)).Int64()
+}
@@ -0,0 +1,155 @@
+package models
+
+import (
+	"math"
+	"testing"
+)
+
+// Babylon onwards bounds the quorum between 20% and 70%
+const (
+	testQuorumMin = 2000
+	testQuorumMax = 7000
+)
+
+func TestParticipation(t *testing.T) {
+	tests := []struct {
+		name  string
+		votes int64
+		total int64
+		want  int64
+	}{
+		// 0.29 * 100 * 100 is 2899.9999 in floating point
+		{name: "no float truncation", votes: 29, total: 100, want: 2900},
+		{name: "truncated", votes: 2, total: 3, want: 6666},
+		{name: "everyone voted", votes: 80000, total: 80000, want: 10000},
+		{name: "nobody voted", votes: 0, total: 80000, want: 0},
+		{name: "no listings", votes: 0, total: 0, want: 0},
+		// Voting power in mutez overflows int64 once multiplied by 10000
+		{name: "mutez", votes: 999999999999999, total: 1000000000000000, want: 9999},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Participation(tt.votes, tt.total); got != tt.want {
+				t.Errorf("Participation(%d, %d) = %d, want %d", tt.votes, tt.total, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewQuorum(t *testing.T) {
+	q := NewQuorum(5827, 7654, testQuorumMin, testQuorumMax)
+	if q.Model != QuorumEMA || q.EMA != 7654 || q.Current != 5827 {
+		t.Errorf("NewQuorum kept %+v, want the EMA returned by the node", q)
+	}
+
+	legacy := NewQuorum(7291, 0, 0, 0)
+	if legacy.Model != QuorumLegacy || legacy.EMA != 0 {
+		t.Errorf("NewQuorum without bounds = %+v, want the legacy model", legacy)
+	}
+}
+
+// TestQuorumNext works the protocol formulas through, the EMA moves a fifth of the way towards the participation
+func TestQuorumNext(t *testing.T) {
+	tests := []struct {
+		name          string
+		quorum        Quorum
+		participation int64
+		wantEMA       int64
+		wantNext      int64
+	}{
+		{
+			name:          "ema",
+			quorum:        NewQuorum(5827, 7654, testQuorumMin, testQuorumMax),
+			participation: 8190,
+			// (8000 * 7654 + 2000 * 8190) / 10000 = 7761.2
+			wantEMA: 7761,
+			// 2000 + 7761 * 5000 / 10000 = 5880.5
+			wantNext: 5880,
+		},
+		{
+			name:          "ema low participation",
+			quorum:        NewQuorum(4500, 5000, testQuorumMin, testQuorumMax),
+			participation: 1234,
+			wantEMA:       4246,
+			wantNext:      4123,
+		},
+		{
+			name:          "legacy",
+			quorum:        NewQuorum(8000, 0, 0, 0),
+			participation: 8193,
+			// (8000 * 8000 + 2000 * 8193) / 10000 = 8038.6
+			wantNext: 8038,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.quorum.Model == QuorumEMA {
+				if got := tt.quorum.NextEMA(tt.participation); got != tt.wantEMA {
+					t.Errorf("NextEMA(%d) = %d, want %d", tt.participation, got, tt.wantEMA)
+				}
+			}
+			if got := tt.quorum.Next(tt.participation); got != tt.wantNext {
+				t.Errorf("Next(%d) = %d, want %d", tt.participation, got, tt.wantNext)
+			}
+		})
+	}
+}
+
+// historicalPeriods are mainnet voting periods as returned by the node, taken from the votes fixtures recorded by
+// go-tezos during the Athens exploration vote
+var historicalPeriods = []struct {
+	name           string
+	quorum         Quorum
+	supermajority  float64
+	yay, nay, pass int64
+	wantQuorum     float64
+	wantYay        float64
+	wantReached    bool
+}{
+	{
+		name: "period 11 exploration, proto 003 PsddFKi3",
+		// votes/current_quorum
+		quorum:        NewQuorum(8000, 0, 0, 0),
+		supermajority: 80,
+		// votes/ballots
+		yay:         26776,
+		nay:         11,
+		pass:        19538,
+		wantQuorum:  80,
+		wantYay:     99.96,
+		wantReached: true,
+	},
+}
+
+func TestHistoricalPeriods(t *testing.T) {
+	for _, p := range historicalPeriods {
+		t.Run(p.name, func(t *testing.T) {
+			if got := p.quorum.Percent(); got != p.wantQuorum {
+				t.Errorf("quorum Percent() = %v, want %v", got, p.wantQuorum)
+			}
+
+			b := &Ballot{Yay: p.yay, Nay: p.nay, Pass: p.pass, Supermajority: p.supermajority, Quorum: p.quorum}
+			if got := math.Round(b.CountingPercentYay()*100) / 100; got != p.wantYay {
+				t.Errorf("CountingPercentYay() = %v, want %v", got, p.wantYay)
+			}
+			if got := b.SupermajorityReached(); got != p.wantReached {
+				t.Errorf("SupermajorityReached() = %v, want %v", got, p.wantReached)
+			}
+		})
+	}
+}
+
+func TestQuorumReached(t *testing.T) {
+	// 2900 of 10000 exactly reaches a 29% quorum, the float participation fell one short
+	b := &Ballot{Yay: 20, Nay: 5, Pass: 4, TotalVotingPower: 100, Quorum: NewQuorum(2900, 1800, testQuorumMin, testQuorumMax)}
+	if !b.QuorumReached() {
+		t.Errorf("QuorumReached() = false at a participation equal to the quorum")
+	}
+
+	r := &BallotResult{Yay: 20, Nay: 5, Pass: 4, TotalVotingPower: 100, Quorum: b.Quorum}
+	if !r.QuorumReached() {
+		t.Errorf("BallotResult.QuorumReached() = false at a participation equal to the quorum")
+	}
+}
@@ -0,0 +1,30 @@
+package models
+
+// Voter is a delegate and its voting power
+type Voter struct {
+	PKH         string
+	VotingPower int64
+}
+
+// FinalReport summarizes a ballot period on its last block
+type FinalReport struct {
+	BlockRef
+
+	Period int
+	// Kind is either exploration or promotion
+	Kind         string
+	ProposalHash string
+	Result       *BallotResult
+	Voters       int
+	NonVoters    int
+	// NonVotersPower is in the same unit as the tallies
+	NonVotersPower int64
+	// TopYay and TopNay are the largest yay and nay voters by decreasing voting power
+	TopYay []Voter
+	TopNay []Voter
+}
+
+// PercentNonVoters returns the voting power of the delegates who did not vote as a percentage of the total voting power
+func (r *FinalReport) PercentNonVoters() float64 {
+	return PercentOf(float64(r.NonVotersPower), float64(r.Result.TotalVotingPower))
+}
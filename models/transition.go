@@ -0,0 +1,89 @@
+package models
+
+import "fmt"
+
+// DefaultSupermajority is the share of yay votes, in percent of yay and nay votes, required to approve a proposal
+const DefaultSupermajority = 80
+
+// BallotResult holds the final tallies of a ballot period
+type BallotResult struct {
+	Yay              int64
+	Nay              int64
+	Pass             int64
+	TotalVotingPower int64
+	Unit             VotingPowerUnit
+	// Supermajority is in percent
+	Quorum        Quorum
+	Supermajority float64
+}
+
+// PercentParticipation returns the voting power which cast a ballot as a percentage of the total voting power
+func (r *BallotResult) PercentParticipation() float64 {
+	return PercentOf(float64(r.Yay+r.Nay+r.Pass), float64(r.TotalVotingPower))
+}
+
+// PercentYay returns the share of yay votes among yay and nay votes
+func (r *BallotResult) PercentYay() float64 {
+	return PercentOf(float64(r.Yay), float64(r.Yay+r.Nay))
+}
+
+// PercentNay returns the share of nay votes among yay and nay votes
+func (r *BallotResult) PercentNay() float64 {
+	return PercentOf(float64(r.Nay), float64(r.Yay+r.Nay))
+}
+
+// PercentPass returns the share of pass votes among all the ballots
+func (r *BallotResult) PercentPass() float64 {
+	return PercentOf(float64(r.Pass), float64(r.Yay+r.Nay+r.Pass))
+}
+
+// participation returns the participation in hundredths of percent, as the protocol computes it
+func (r *BallotResult) participation() int64 {
+	return Participation(r.Yay+r.Nay+r.Pass, r.TotalVotingPower)
+}
+
+// QuorumReached returns true if the participation reached the quorum
+func (r *BallotResult) QuorumReached() bool {
+	return r.participation() >= r.Quorum.Current
+}
+
+// NextQuorum returns the quorum in percent of the next ballot period resulting from this participation
+func (r *BallotResult) NextQuorum() float64 {
+	return r.Quorum.NextPercent(r.participation())
+}
+
+// SupermajorityReached returns true if the yay votes reached the supermajority
+func (r *BallotResult) SupermajorityReached() bool {
+	return r.Yay+r.Nay > 0 && r.PercentYay() >= r.Supermajority
+}
+
+// Passed returns true if the proposal was approved
+func (r *BallotResult) Passed() bool {
+	return r.QuorumReached() && r.SupermajorityReached()
+}
+
+// FailureReason explains why the proposal was rejected, empty if it passed
+func (r *BallotResult) FailureReason() string {
+	switch {
+	case !r.QuorumReached():
+		return fmt.Sprintf("participation of %.2f%% did not reach the %.2f%% quorum", r.PercentParticipation(), r.Quorum.Percent())
+	case !r.SupermajorityReached():
+		return fmt.Sprintf("%.2f%% Yay did not reach the %.2f%% supermajority", r.PercentYay(), r.Supermajority)
+	}
+	return ""
+}
+
+// PeriodTransition is emitted when the chain moves from a voting period to the next one
+type PeriodTransition struct {
+	BlockRef
+
+	FromIndex int
+	// FromKind and ToKind are one of proposal, exploration, cooldown, promotion or adoption
+	FromKind string
+	ToIndex  int
+	ToKind   string
+	// ProposalHash is the proposal voted on during the ended period, or the proposal which won the ended proposal period
+	ProposalHash string
+	// Result is only set when the ended period was a ballot period
+	Result *BallotResult
+}
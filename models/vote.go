@@ -1,28 +1,38 @@
 package models
 
+import (
+	"math"
+	"math/big"
+)
+
 // Ballot is a struct holding tezos ballot information
 type Ballot struct {
+	BlockRef
+
 	PKH          string
 	Ballot       string
 	ProposalHash string
-	Rolls        int64
+	VotingPower  int64
+	Unit         VotingPowerUnit
 	IsTesting    bool
 	Period       int
 
-	// General statistic
-	Quorum     float64
-	TotalRolls float64
-	Yay        int64
-	Nay        int64
-	Pass       int64
+	// General statistic, tallies are in the same unit as the voting power
+	Quorum           Quorum
+	Supermajority    float64
+	TotalVotingPower float64
+	Yay              int64
+	Nay              int64
+	Pass             int64
 }
 
 func (b *Ballot) PercentParticipation() float64 {
-	if b.TotalRolls == 0 {
-		return 0
-	}
+	return PercentOf(b.Participations(), b.TotalVotingPower)
+}
 
-	return (b.Participations() / b.TotalRolls) * 100
+// PercentOfTotal returns the voting power of the baker as a percentage of the total voting power
+func (b *Ballot) PercentOfTotal() float64 {
+	return PercentOf(float64(b.VotingPower), b.TotalVotingPower)
 }
 
 func (b *Ballot) CountingPercentYay() float64 {
@@ -73,8 +83,68 @@ func (b *Ballot) CountingParticipations() float64 {
 	return float64(b.Yay + b.Nay)
 }
 
+// participation returns the participation in hundredths of percent, as the protocol computes it
+func (b *Ballot) participation() int64 {
+	return Participation(b.Yay+b.Nay+b.Pass, int64(b.TotalVotingPower))
+}
+
+// QuorumReached returns true if the participation reached the quorum, compared in hundredths of percent like the protocol
+func (b *Ballot) QuorumReached() bool {
+	return b.participation() >= b.Quorum.Current
+}
+
+// PercentTowardQuorum returns the participation still missing to reach the quorum in percent, 0 once reached
 func (b *Ballot) PercentTowardQuorum() float64 {
-	return b.Quorum - b.PercentParticipation()
+	if b.QuorumReached() {
+		return 0
+	}
+	return float64(b.Quorum.Current-b.participation()) / 100
+}
+
+// ProjectedQuorum returns the quorum of the next ballot period in percent if the period ended with the current participation
+func (b *Ballot) ProjectedQuorum() float64 {
+	return b.Quorum.NextPercent(b.participation())
+}
+
+// SupermajorityReached returns true if the yay votes currently reach the supermajority
+func (b *Ballot) SupermajorityReached() bool {
+	return b.CountingParticipations() > 0 && b.CountingPercentYay() >= b.Supermajority
+}
+
+// WouldPass returns true if the proposal would be approved if the period ended now
+func (b *Ballot) WouldPass() bool {
+	return b.QuorumReached() && b.SupermajorityReached()
+}
+
+// YayNeededForSupermajority returns the additional yay voting power needed to reach the supermajority, 0 when no yay
+// or nay vote was cast yet
+func (b *Ballot) YayNeededForSupermajority() int64 {
+	if b.SupermajorityReached() {
+		return 0
+	}
+	// (yay + x) / (yay + x + nay) >= s  <=>  x >= s * nay / (1 - s) - yay
+	bp := basisPoints(b.Supermajority)
+	if bp <= 0 || bp >= 10000 {
+		return 0
+	}
+	needed := mulDiv(b.Nay, bp, 10000-bp, true) - b.Yay
+	if needed < 0 {
+		return 0
+	}
+	return needed
+}
+
+// NayToBlockSupermajority returns the additional nay voting power that would bring the yay votes below the supermajority
+func (b *Ballot) NayToBlockSupermajority() int64 {
+	if !b.SupermajorityReached() {
+		return 0
+	}
+	// yay / (yay + nay + y) < s  <=>  y > yay * (1 - s) / s - nay
+	bp := basisPoints(b.Supermajority)
+	if bp <= 0 || bp >= 10000 {
+		return 0
+	}
+	return mulDiv(b.Yay, 10000-bp, bp, false) - b.Nay + 1
 }
 
 func (b *Ballot) Phase() string {
@@ -83,3 +153,19 @@ func (b *Ballot) Phase() string {
 	}
 	return "promotion"
 }
+
+// basisPoints converts a percentage to basis points
+func basisPoints(percent float64) int64 {
+	return int64(math.Round(percent * 100))
+}
+
+// mulDiv returns a * b / c rounded up or down without overflowing, c must not be 0
+func mulDiv(a, b, c int64, roundUp bool) int64 {
+	n := new(big.Int).Mul(big.NewInt(a), big.NewInt(b))
+	d := big.NewInt(c)
+	q, m := new(big.Int).DivMod(n, d, new(big.Int))
+	if roundUp && m.Sign() != 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	return q.Int64()
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/ecadlabs/tezos-bot/config"
+	"github.com/ecadlabs/tezos-bot/health"
+	"github.com/ecadlabs/tezos-bot/listen"
+	"github.com/ecadlabs/tezos-bot/models"
+	"github.com/ecadlabs/tezos-bot/publish"
+	"github.com/ecadlabs/tezos-bot/service"
+)
+
+// previewEvent is an event as recorded in the event store, served by the api or streamed
+type previewEvent struct {
+	Kind    string          `json:"kind"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// previewPublisher is a publisher rendering the messages instead of publishing them
+type previewPublisher struct {
+	name      string
+	publisher service.VotePublisher
+}
+
+func previewPublishers(c config.Config) []previewPublisher {
+	return []previewPublisher{
+		{"debug", &publish.DebugPublisher{Mentions: c.GetTwitterMentions()}},
+		{"twitter", publish.NewTwitterPreviewPublisher(c, os.Stdout)},
+	}
+}
+
+// twitterPreviewer renders the tweets of events for the admin ui
+type twitterPreviewer struct {
+	config config.Config
+}
+
+// Preview returns the tweet of an event with its length
+func (p twitterPreviewer) Preview(kind string, payload json.RawMessage) (string, error) {
+	var buf bytes.Buffer
+	err := service.Dispatch(publish.NewTwitterPreviewPublisher(p.config, &buf), kind, payload)
+	return buf.String(), err
+}
+
+// previewSink renders the messages of every event detected by the listener
+type previewSink struct {
+	publishers []previewPublisher
+	only       string
+	count      int
+	failed     bool
+}
+
+// Send renders the messages of an event
+func (p *previewSink) Send(kind string, ref models.BlockRef, event interface{}) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	p.count++
+	if ref.Level != 0 {
+		fmt.Printf("== #%d %s at level %d\n", p.count, kind, ref.Level)
+	} else {
+		fmt.Printf("== #%d %s\n", p.count, kind)
+	}
+	p.render(kind, payload)
+	return nil
+}
+
+func (p *previewSink) render(kind string, payload json.RawMessage) {
+	for _, pub := range p.publishers {
+		if p.only != "" && p.only != pub.name {
+			continue
+		}
+		fmt.Printf("-- %s\n", pub.name)
+		if err := service.Dispatch(pub.publisher, kind, payload); err != nil {
+			fmt.Printf("error: %s\n", err.Error())
+			p.failed = true
+		}
+	}
+	fmt.Println()
+}
+
+func runPreview(args []string) int {
+	fs := newFlagSet("preview", "[flags] [<event-file>]", "Render the messages the bot would post, for each publisher and without publishing them, along with\ntheir length and whether they fit the platform limits.\n\nThe events are either read from a file, holding events as recorded in the event store or served by the api,\n{\"kind\": ..., \"payload\": ...}, as a JSON array or one per line, or detected by running the detectors on\na block or a voting period.")
+	configFile := configFlag(fs)
+	only := fs.String("publisher", "", "Only render the messages of this publisher, debug or twitter.")
+	level := fs.Int("level", 0, "Run the detectors on the block at this level.")
+	block := fs.String("block", "", "Run the detectors on the block with this hash.")
+	period := fs.Int("period", -1, "Run the detectors on every block of this voting period.")
+
+	// The event file may come before the flags
+	file := ""
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		file, args = args[0], args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if file == "" {
+		file = fs.Arg(0)
+	}
+
+	sources := 0
+	for _, set := range []bool{file != "", *level > 0, *block != "", *period >= 0} {
+		if set {
+			sources++
+		}
+	}
+	if sources != 1 {
+		fmt.Fprintln(os.Stderr, "preview: exactly one of an event file, -level, -block or -period is required")
+		fs.Usage()
+		return 2
+	}
+
+	c, err := loadConfig(*configFile)
+	if err != nil {
+		log.Printf(err.Error())
+		return 1
+	}
+
+	sink := &previewSink{publishers: previewPublishers(c), only: *only}
+
+	if file != "" {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			log.Printf(err.Error())
+			return 1
+		}
+		events, err := parseEvents(data)
+		if err != nil {
+			log.Printf("Unable to read events from %s: %s\n", file, err.Error())
+			return 1
+		}
+		for i, e := range events {
+			fmt.Printf("== #%d %s\n", i+1, e.Kind)
+			sink.render(e.Kind, e.Payload)
+		}
+	} else {
+		from, to, err := previewLevels(c, *level, *block, *period)
+		if err != nil {
+			log.Printf(err.Error())
+			return 1
+		}
+
+		c.History = true
+		c.HistoryStartingBlock = from
+		c.HistoryEndingBlock = to
+		log.Printf("Running the detectors on levels %d to %d\n", from, to)
+		if code := runBot(c, modePreview, sink); code != 0 {
+			return code
+		}
+		if sink.count == 0 {
+			fmt.Println("Nothing would be posted")
+		}
+	}
+
+	if sink.failed {
+		return 1
+	}
+	return 0
+}
+
+// previewLevels returns the range of levels to run the detectors on
+func previewLevels(c config.Config, level int, block string, period int) (int, int, error) {
+	if level > 0 {
+		return level, level, nil
+	}
+
+	exporter, err := listen.NewExporter(c, health.NewStatus())
+	if err != nil {
+		return 0, 0, err
+	}
+
+	ctx := context.Background()
+	if block != "" {
+		level, err := exporter.Level(ctx, block)
+		return level, level, err
+	}
+	return exporter.PeriodLevels(ctx, period)
+}
+
+// parseEvents reads a JSON array of events or a stream of JSON events
+func parseEvents(data []byte) ([]*previewEvent, error) {
+	data = bytes.TrimSpace(data)
+	events := []*previewEvent{}
+	if bytes.HasPrefix(data, []byte("[")) {
+		return events, json.Unmarshal(data, &events)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for {
+		e := &previewEvent{}
+		if err := dec.Decode(e); err == io.EOF {
+			return events, nil
+		} else if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+}
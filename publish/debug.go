@@ -7,7 +7,10 @@ import (
 )
 
 // DebugPublisher is a simple publish that logs ballot directly to stdout
-type DebugPublisher struct{}
+type DebugPublisher struct {
+	// Mentions maps the addresses of the bakers who opted in to be mentioned to their handle
+	Mentions map[string]string
+}
 
 // Publish logs ballot directly to stdout
 func (d *DebugPublisher) Publish(ballot *models.Ballot) error {
@@ -53,3 +56,93 @@ func (d *DebugPublisher) PublishProposalUpvote(proposal *models.Proposal) error
 	fmt.Printf("(%d) %s\n", len(status), status)
 	return nil
 }
+
+// PublishNewPeriod a new voting period message to stdout
+func (d *DebugPublisher) PublishNewPeriod(period *models.VotingPeriod) error {
+	status, err := GetNewPeriodString(period)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("(%d) %s\n", len(status), status)
+	return nil
+}
+
+// PublishActivationCountdown a new activation countdown message to stdout
+func (d *DebugPublisher) PublishActivationCountdown(countdown *models.ActivationCountdown) error {
+	status, err := GetActivationCountdownString(countdown)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("(%d) %s\n", len(status), status)
+	return nil
+}
+
+// PublishPeriodTransition a new period transition message to stdout
+func (d *DebugPublisher) PublishPeriodTransition(transition *models.PeriodTransition) error {
+	status, err := GetPeriodTransitionString(transition)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("(%d) %s\n", len(status), status)
+	return nil
+}
+
+// PublishFinalReport the detailed final report of a ballot period to stdout
+func (d *DebugPublisher) PublishFinalReport(report *models.FinalReport) error {
+	status, err := GetFinalReportLongString(report)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("(%d) %s\n", len(status), status)
+	return nil
+}
+
+// PublishBallotDigest a new ballot digest message to stdout
+func (d *DebugPublisher) PublishBallotDigest(digest *models.BallotDigest) error {
+	status, err := GetBallotDigestString(digest)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("(%d) %s\n", len(status), status)
+	return nil
+}
+
+// PublishMilestone a new vote milestone message to stdout
+func (d *DebugPublisher) PublishMilestone(milestone *models.Milestone) error {
+	status, err := GetMilestoneString(milestone)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("(%d) %s\n", len(status), status)
+	return nil
+}
+
+// PublishNonVoterReport a new non voter report message to stdout
+func (d *DebugPublisher) PublishNonVoterReport(report *models.NonVoterReport) error {
+	status, err := GetNonVoterReportString(report, d.Mentions)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("(%d) %s\n", len(status), status)
+	return nil
+}
+
+// PublishProposalLeaderboard a new proposal leaderboard message to stdout
+func (d *DebugPublisher) PublishProposalLeaderboard(leaderboard *models.ProposalLeaderboard) error {
+	status, err := GetProposalLeaderboardString(leaderboard)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("(%d) %s\n", len(status), status)
+	return nil
+}
+
+// PublishAnnouncement a new announcement message to stdout
+func (d *DebugPublisher) PublishAnnouncement(announcement *models.Announcement) error {
+	status, err := GetAnnouncementString(announcement)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("(%d) %s\n", len(status), status)
+	return nil
+}
@@ -9,6 +9,7 @@ import (
 	"strconv"
 	"strings"
 	"text/template"
+	"time"
 
 	"github.com/ecadlabs/tezos-bot/models"
 )
@@ -19,47 +20,454 @@ const (
 )
 
 type statusTmplData struct {
-	AccountName         string
-	ProposalName        string
-	Rolls               int64
-	Phase               string
-	Voted               string
-	Period              int
-	Ballot              string
-	PercentYay          float64
-	PercentNay          float64
-	PercentTowardQuorum float64
-	Quorum              float64
-	QuorumReached       bool
+	AccountName          string
+	ProposalName         string
+	VotingPower          string
+	PercentOfTotal       float64
+	Phase                string
+	Voted                string
+	Period               int
+	Ballot               string
+	PercentYay           float64
+	PercentNay           float64
+	PercentTowardQuorum  float64
+	Quorum               float64
+	QuorumReached        bool
+	ParticipationEMA     float64
+	ProjectedQuorum      float64
+	Supermajority        float64
+	SupermajorityReached bool
+	YayNeeded            string
+	NayToBlock           string
+	WouldPass            bool
 }
 
 func Percent(s float64) string {
 	return fmt.Sprintf("%s%%", strconv.FormatFloat(math.Round(s*100)/100, 'f', -1, 64))
 }
 
+// VotingPower formats a voting power in its unit, rolls or tez
+func VotingPower(power int64, unit models.VotingPowerUnit) string {
+	if unit == models.Rolls {
+		if power == 1 {
+			return "1 roll"
+		}
+		return fmt.Sprintf("%d rolls", power)
+	}
+	return fmt.Sprintf("%s tez", groupThousands(int64(math.Round(float64(power)/models.MutezPerTez))))
+}
+
+// groupThousands formats n with comma separated groups of thousands
+func groupThousands(n int64) string {
+	s := strconv.FormatInt(n, 10)
+	sign := ""
+	if n < 0 {
+		sign, s = "-", s[1:]
+	}
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return sign + s
+}
+
+type periodTmplData struct {
+	Index           int
+	StartLevel      int
+	EndLevel        int
+	ActivationLevel int
+	EstimatedEnd    time.Time
+	ProposalName    string
+}
+
+type countdownTmplData struct {
+	ProposalName        string
+	ActivationLevel     int
+	BlocksRemaining     int
+	Remaining           string
+	EstimatedActivation time.Time
+}
+
+type transitionTmplData struct {
+	FromIndex    int
+	FromKind     string
+	ToIndex      int
+	ToKind       string
+	ProposalName string
+	Result       *models.BallotResult
+}
+
+type voterTmplData struct {
+	Name           string
+	VotingPower    string
+	PercentOfTotal float64
+}
+
+type reportTmplData struct {
+	Period           int
+	Kind             string
+	ProposalName     string
+	Result           *models.BallotResult
+	Yay              string
+	Nay              string
+	Pass             string
+	TotalVotingPower string
+	Voters           int
+	NonVoters        int
+	NonVotersPower   string
+	PercentNonVoters float64
+	TopYay           []voterTmplData
+	TopNay           []voterTmplData
+}
+
+// digestTopVoters is the number of voters listed in a ballot digest
+const digestTopVoters = 3
+
+type digestVoterTmplData struct {
+	Name        string
+	Ballot      string
+	VotingPower string
+}
+
+type digestTmplData struct {
+	ProposalName         string
+	Phase                string
+	Period               int
+	Count                int
+	Yay                  int
+	Nay                  int
+	Pass                 int
+	TopVoters            []digestVoterTmplData
+	PercentYay           float64
+	PercentNay           float64
+	PercentParticipation float64
+	Quorum               float64
+	QuorumReached        bool
+}
+
+type milestoneTmplData struct {
+	Kind         string
+	Period       int
+	Phase        string
+	ProposalName string
+	Threshold    float64
+	Result       *models.BallotResult
+}
+
+type nonVoterTmplData struct {
+	Name        string
+	VotingPower string
+	Mention     string
+}
+
+type nonVotersTmplData struct {
+	Period           int
+	Phase            string
+	ProposalName     string
+	LastCycle        bool
+	BlocksRemaining  int
+	NonVoters        int
+	PercentNonVoters float64
+	Top              []nonVoterTmplData
+}
+
+type leaderboardEntryTmplData struct {
+	Rank           int
+	Name           string
+	VotingPower    string
+	Delta          string
+	PercentOfTotal float64
+	Supporters     int
+	AboveQuorum    bool
+}
+
+type leaderboardTmplData struct {
+	Period             int
+	Cycle              int
+	Entries            []leaderboardEntryTmplData
+	MinProposalQuorum  float64
+	SupportersComplete bool
+}
+
+// Date formats an estimated time in UTC
+func Date(t time.Time) string {
+	return t.UTC().Format("Jan 2 15:04 UTC")
+}
+
+// Duration formats a duration in hours or minutes
+func Duration(d time.Duration) string {
+	plural := func(n int64, unit string) string {
+		if n == 1 {
+			return fmt.Sprintf("1 %s", unit)
+		}
+		return fmt.Sprintf("%d %ss", n, unit)
+	}
+
+	if d >= time.Hour {
+		return plural(int64(math.Round(d.Hours())), "hour")
+	}
+	return plural(int64(math.Ceil(d.Minutes())), "minute")
+}
+
 var (
 	funcMap = template.FuncMap{
 		"Title":   strings.Title,
 		"Percent": Percent,
+		"Date":    Date,
 	}
-	statusTmpl = template.Must(template.New("status.tmpl").Funcs(funcMap).ParseFiles("./templates/status.tmpl"))
+	statusTmpl      = template.Must(template.New("status.tmpl").Funcs(funcMap).ParseFiles("./templates/status.tmpl"))
+	periodTmpl      = template.Must(template.New("period").Funcs(funcMap).ParseGlob("./templates/period_*.tmpl"))
+	countdownTmpl   = template.Must(template.New("countdown.tmpl").Funcs(funcMap).ParseFiles("./templates/countdown.tmpl"))
+	transitionTmpl  = template.Must(template.New("transition.tmpl").Funcs(funcMap).ParseFiles("./templates/transition.tmpl"))
+	digestTmpl      = template.Must(template.New("digest.tmpl").Funcs(funcMap).ParseFiles("./templates/digest.tmpl"))
+	milestoneTmpl   = template.Must(template.New("milestone.tmpl").Funcs(funcMap).ParseFiles("./templates/milestone.tmpl"))
+	nonVotersTmpl   = template.Must(template.New("nonvoters.tmpl").Funcs(funcMap).ParseFiles("./templates/nonvoters.tmpl"))
+	leaderboardTmpl = template.Must(template.New("leaderboard.tmpl").Funcs(funcMap).ParseFiles("./templates/leaderboard.tmpl"))
+	reportTmpl      = template.Must(template.New("report").Funcs(funcMap).ParseFiles("./templates/report.tmpl", "./templates/report_long.tmpl"))
+	announceTmpl    = template.Must(template.New("announce").Funcs(funcMap).Option("missingkey=error").ParseGlob("./templates/announce_*.tmpl"))
 )
 
 // GetStatusString composes a status string based on available vanity data
 func GetStatusString(ballot *models.Ballot) (string, error) {
+	// Nothing is needed yet when no yay or nay vote was cast
+	yayNeeded := ""
+	if needed := ballot.YayNeededForSupermajority(); needed > 0 {
+		yayNeeded = VotingPower(needed, ballot.Unit)
+	}
+
 	var tpl bytes.Buffer
 	if err := statusTmpl.Execute(&tpl, statusTmplData{
-		AccountName:         lookupOrDefault(ballot.PKH, addressZone),
-		Rolls:               ballot.Rolls,
-		ProposalName:        lookupOrDefault(ballot.ProposalHash, proposalZone),
-		Phase:               ballot.Phase(),
-		Period:              ballot.Period,
-		Ballot:              ballot.Ballot,
-		PercentYay:          ballot.CountingPercentYay(),
-		PercentNay:          ballot.CountingPercentNay(),
-		PercentTowardQuorum: ballot.PercentTowardQuorum(),
-		Quorum:              ballot.Quorum,
-		QuorumReached:       ballot.PercentTowardQuorum() <= 0,
+		AccountName:          lookupOrDefault(ballot.PKH, addressZone),
+		VotingPower:          VotingPower(ballot.VotingPower, ballot.Unit),
+		PercentOfTotal:       ballot.PercentOfTotal(),
+		ProposalName:         lookupOrDefault(ballot.ProposalHash, proposalZone),
+		Phase:                ballot.Phase(),
+		Period:               ballot.Period,
+		Ballot:               ballot.Ballot,
+		PercentYay:           ballot.CountingPercentYay(),
+		PercentNay:           ballot.CountingPercentNay(),
+		PercentTowardQuorum:  ballot.PercentTowardQuorum(),
+		Quorum:               ballot.Quorum.Percent(),
+		QuorumReached:        ballot.QuorumReached(),
+		ParticipationEMA:     ballot.Quorum.EMAPercent(),
+		ProjectedQuorum:      ballot.ProjectedQuorum(),
+		Supermajority:        ballot.Supermajority,
+		SupermajorityReached: ballot.SupermajorityReached(),
+		YayNeeded:            yayNeeded,
+		NayToBlock:           VotingPower(ballot.NayToBlockSupermajority(), ballot.Unit),
+		WouldPass:            ballot.WouldPass(),
+	}); err != nil {
+		return "", err
+	}
+	return tpl.String(), nil
+}
+
+// GetNewPeriodString composes a status string announcing a new voting period
+func GetNewPeriodString(period *models.VotingPeriod) (string, error) {
+	proposalName := ""
+	if period.ProposalHash != "" {
+		proposalName = lookupOrDefault(period.ProposalHash, proposalZone)
+	}
+
+	var tpl bytes.Buffer
+	if err := periodTmpl.ExecuteTemplate(&tpl, fmt.Sprintf("period_%s.tmpl", period.Kind), periodTmplData{
+		Index:           period.Index,
+		StartLevel:      period.StartLevel,
+		EndLevel:        period.EndLevel,
+		ActivationLevel: period.ActivationLevel(),
+		EstimatedEnd:    period.EstimatedEnd,
+		ProposalName:    proposalName,
+	}); err != nil {
+		return "", err
+	}
+	return tpl.String(), nil
+}
+
+// GetActivationCountdownString composes a status string counting down to a protocol activation
+func GetActivationCountdownString(countdown *models.ActivationCountdown) (string, error) {
+	var tpl bytes.Buffer
+	if err := countdownTmpl.Execute(&tpl, countdownTmplData{
+		ProposalName:        lookupOrDefault(countdown.ProposalHash, proposalZone),
+		ActivationLevel:     countdown.ActivationLevel,
+		BlocksRemaining:     countdown.BlocksRemaining,
+		Remaining:           Duration(countdown.TimeRemaining),
+		EstimatedActivation: countdown.EstimatedActivation,
+	}); err != nil {
+		return "", err
+	}
+	return tpl.String(), nil
+}
+
+// GetPeriodTransitionString composes a status string with the outcome of the period that ended
+func GetPeriodTransitionString(transition *models.PeriodTransition) (string, error) {
+	proposalName := ""
+	if transition.ProposalHash != "" {
+		proposalName = lookupOrDefault(transition.ProposalHash, proposalZone)
+	}
+
+	var tpl bytes.Buffer
+	if err := transitionTmpl.Execute(&tpl, transitionTmplData{
+		FromIndex:    transition.FromIndex,
+		FromKind:     transition.FromKind,
+		ToIndex:      transition.ToIndex,
+		ToKind:       transition.ToKind,
+		ProposalName: proposalName,
+		Result:       transition.Result,
+	}); err != nil {
+		return "", err
+	}
+	return tpl.String(), nil
+}
+
+// GetFinalReportString composes a status string with the final results of a ballot period
+func GetFinalReportString(report *models.FinalReport) (string, error) {
+	return executeReport("report.tmpl", report)
+}
+
+// GetFinalReportLongString composes a detailed final report of a ballot period for sinks without length limit
+func GetFinalReportLongString(report *models.FinalReport) (string, error) {
+	return executeReport("report_long.tmpl", report)
+}
+
+func executeReport(name string, report *models.FinalReport) (string, error) {
+	result := report.Result
+	voters := func(list []models.Voter) []voterTmplData {
+		data := make([]voterTmplData, len(list))
+		for i, v := range list {
+			data[i] = voterTmplData{
+				Name:           lookupOrDefault(v.PKH, addressZone),
+				VotingPower:    VotingPower(v.VotingPower, result.Unit),
+				PercentOfTotal: models.PercentOf(float64(v.VotingPower), float64(result.TotalVotingPower)),
+			}
+		}
+		return data
+	}
+
+	var tpl bytes.Buffer
+	if err := reportTmpl.ExecuteTemplate(&tpl, name, reportTmplData{
+		Period:           report.Period,
+		Kind:             report.Kind,
+		ProposalName:     lookupOrDefault(report.ProposalHash, proposalZone),
+		Result:           result,
+		Yay:              VotingPower(result.Yay, result.Unit),
+		Nay:              VotingPower(result.Nay, result.Unit),
+		Pass:             VotingPower(result.Pass, result.Unit),
+		TotalVotingPower: VotingPower(result.TotalVotingPower, result.Unit),
+		Voters:           report.Voters,
+		NonVoters:        report.NonVoters,
+		NonVotersPower:   VotingPower(report.NonVotersPower, result.Unit),
+		PercentNonVoters: report.PercentNonVoters(),
+		TopYay:           voters(report.TopYay),
+		TopNay:           voters(report.TopNay),
+	}); err != nil {
+		return "", err
+	}
+	return tpl.String(), nil
+}
+
+// GetBallotDigestString composes a status string aggregating the ballots of a digest window
+func GetBallotDigestString(digest *models.BallotDigest) (string, error) {
+	latest := digest.Latest()
+	if latest == nil {
+		return "", fmt.Errorf("empty ballot digest")
+	}
+
+	top := digest.TopVoters(digestTopVoters)
+	voters := make([]digestVoterTmplData, len(top))
+	for i, ballot := range top {
+		voters[i] = digestVoterTmplData{
+			Name:        lookupOrDefault(ballot.PKH, addressZone),
+			Ballot:      ballot.Ballot,
+			VotingPower: VotingPower(ballot.VotingPower, ballot.Unit),
+		}
+	}
+
+	var tpl bytes.Buffer
+	if err := digestTmpl.Execute(&tpl, digestTmplData{
+		ProposalName:         lookupOrDefault(digest.ProposalHash, proposalZone),
+		Phase:                latest.Phase(),
+		Period:               digest.Period,
+		Count:                len(digest.Ballots),
+		Yay:                  digest.Count("yay"),
+		Nay:                  digest.Count("nay"),
+		Pass:                 digest.Count("pass"),
+		TopVoters:            voters,
+		PercentYay:           latest.CountingPercentYay(),
+		PercentNay:           latest.CountingPercentNay(),
+		PercentParticipation: latest.PercentParticipation(),
+		Quorum:               latest.Quorum.Percent(),
+		QuorumReached:        latest.QuorumReached(),
+	}); err != nil {
+		return "", err
+	}
+	return tpl.String(), nil
+}
+
+// GetMilestoneString composes a status string announcing a vote milestone
+func GetMilestoneString(milestone *models.Milestone) (string, error) {
+	var tpl bytes.Buffer
+	if err := milestoneTmpl.Execute(&tpl, milestoneTmplData{
+		Kind:         milestone.Kind,
+		Period:       milestone.Period,
+		Phase:        milestone.Phase,
+		ProposalName: lookupOrDefault(milestone.ProposalHash, proposalZone),
+		Threshold:    milestone.Threshold,
+		Result:       milestone.Result,
+	}); err != nil {
+		return "", err
+	}
+	return tpl.String(), nil
+}
+
+// GetNonVoterReportString composes a status string reminding the bakers who did not vote yet,
+// mentions maps the addresses of the bakers who opted in to be mentioned to their handle
+func GetNonVoterReportString(report *models.NonVoterReport, mentions map[string]string) (string, error) {
+	top := make([]nonVoterTmplData, len(report.Top))
+	for i, v := range report.Top {
+		top[i] = nonVoterTmplData{
+			Name:        lookupOrDefault(v.PKH, addressZone),
+			VotingPower: VotingPower(v.VotingPower, report.Unit),
+			Mention:     mentions[v.PKH],
+		}
+	}
+
+	var tpl bytes.Buffer
+	if err := nonVotersTmpl.Execute(&tpl, nonVotersTmplData{
+		Period:           report.Period,
+		Phase:            report.Phase,
+		ProposalName:     lookupOrDefault(report.ProposalHash, proposalZone),
+		LastCycle:        report.LastCycle,
+		BlocksRemaining:  report.BlocksRemaining,
+		NonVoters:        report.NonVoters,
+		PercentNonVoters: report.PercentNonVoters(),
+		Top:              top,
+	}); err != nil {
+		return "", err
+	}
+	return tpl.String(), nil
+}
+
+// GetProposalLeaderboardString composes a status string ranking all the proposals of a proposal period
+func GetProposalLeaderboardString(leaderboard *models.ProposalLeaderboard) (string, error) {
+	entries := make([]leaderboardEntryTmplData, len(leaderboard.Entries))
+	for i, entry := range leaderboard.Entries {
+		entries[i] = leaderboardEntryTmplData{
+			Rank:           i + 1,
+			Name:           lookupOrDefault(entry.ProposalHash, proposalZone),
+			VotingPower:    VotingPower(entry.VotingPower, leaderboard.Unit),
+			Delta:          VotingPower(entry.Delta, leaderboard.Unit),
+			PercentOfTotal: leaderboard.PercentOfTotal(entry),
+			Supporters:     entry.Supporters,
+			AboveQuorum:    entry.AboveQuorum,
+		}
+	}
+
+	var tpl bytes.Buffer
+	if err := leaderboardTmpl.Execute(&tpl, leaderboardTmplData{
+		Period:             leaderboard.Period,
+		Cycle:              leaderboard.Cycle + 1,
+		Entries:            entries,
+		MinProposalQuorum:  leaderboard.MinProposalQuorum,
+		SupportersComplete: leaderboard.SupportersComplete,
 	}); err != nil {
 		return "", err
 	}
@@ -87,7 +495,7 @@ func GetProposalSummaryString(summary *models.ProposalSummary) string {
 		proposalName = fmt.Sprintf("%s (%s)", proposalName, summary.ProposalHash)
 	}
 
-	return fmt.Sprintf("Proposal upvotes: #Tezos proposal %s received %d upvotes in cycle %d, and now has %d votes.", proposalName, summary.NewSupporters, summary.Cycle, summary.SupporterCount)
+	return fmt.Sprintf("Proposal upvotes: #Tezos proposal %s received %s of upvotes in cycle %d, and now has %s (%s of voting power).", proposalName, VotingPower(summary.NewVotingPower, summary.Unit), summary.Cycle, VotingPower(summary.VotingPower, summary.Unit), Percent(summary.PercentOfTotal()))
 }
 
 // GetWinningProposalString get status message for proposal that moved to exploration phase
@@ -101,7 +509,7 @@ func GetWinningProposalString(summary *models.ProposalSummary) string {
 		proposalName = fmt.Sprintf("%s (%s)", proposalName, summary.ProposalHash)
 	}
 
-	return fmt.Sprintf("Proposal period complete: proposal %s received the most upvotes (%d) and is advancing to the exploration vote period.", proposalName, summary.SupporterCount)
+	return fmt.Sprintf("Proposal period complete: proposal %s received the most upvotes (%s, %s of voting power) and is advancing to the exploration vote period.", proposalName, VotingPower(summary.VotingPower, summary.Unit), Percent(summary.PercentOfTotal()))
 }
 
 // GetProposalInjectString retrieve the template for proposal injection status
@@ -130,8 +538,8 @@ func GetProposalUpvoteString(proposal *models.Proposal) string {
 	}
 
 	templateRolls := ""
-	if proposal.Rolls != 0 {
-		templateRolls = fmt.Sprintf("with %d rolls ", proposal.Rolls)
+	if proposal.VotingPower != 0 {
+		templateRolls = fmt.Sprintf("with %s (%s of voting power) ", VotingPower(proposal.VotingPower, proposal.Unit), Percent(proposal.PercentOfTotal()))
 	}
 
 	templateAddress := proposal.PKH
@@ -168,3 +576,30 @@ func LookupTZName(address, zone string) (string, error) {
 	}
 	return string(rrs[0]), nil
 }
+
+// GetAnnouncementString composes a status string from an announcement template, or returns the free text of the announcement
+func GetAnnouncementString(announcement *models.Announcement) (string, error) {
+	if announcement.Template == "" {
+		text := strings.TrimSpace(announcement.Text)
+		if text == "" {
+			return "", fmt.Errorf("announcement has neither a template nor a text")
+		}
+		return text, nil
+	}
+
+	name := fmt.Sprintf("announce_%s.tmpl", announcement.Template)
+	if announceTmpl.Lookup(name) == nil {
+		return "", fmt.Errorf("unknown announcement template %q", announcement.Template)
+	}
+
+	vars := announcement.Vars
+	if vars == nil {
+		vars = map[string]string{}
+	}
+
+	var tpl bytes.Buffer
+	if err := announceTmpl.ExecuteTemplate(&tpl, name, vars); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(tpl.String()), nil
+}
@@ -1,11 +1,14 @@
 package publish
 
 import (
+	"fmt"
+	"io"
 	"log"
 
 	"github.com/dghubble/go-twitter/twitter"
 	"github.com/dghubble/oauth1"
 	"github.com/ecadlabs/tezos-bot/models"
+	"github.com/ecadlabs/tezos-bot/tweet"
 )
 
 // TwitterConfig interface with method necessary to obtain twitter publisher configurable parameter
@@ -14,11 +17,13 @@ type TwitterConfig interface {
 	GetTwitterConsummerKey() string
 	GetTwitterAccessTokenSecret() string
 	GetTwitterAccessToken() string
+	GetTwitterMentions() map[string]string
 }
 
 // TwitterPublisher publisher that post new ballot on twitter
 type TwitterPublisher struct {
-	client *twitter.Client
+	post     func(status string) error
+	mentions map[string]string
 }
 
 // NewTwitterPublisher create a new TwitterPublisher
@@ -41,69 +46,152 @@ func NewTwitterPublisher(config TwitterConfig) (*TwitterPublisher, error) {
 	}
 
 	return &TwitterPublisher{
-		client: client,
+		post: func(status string) error {
+			_, _, err := client.Statuses.Update(status, nil)
+			if err == nil {
+				log.Printf("(twitter) Published status: %s\n", status)
+			}
+			return err
+		},
+		mentions: config.GetTwitterMentions(),
 	}, nil
 }
 
+// NewTwitterPreviewPublisher create a TwitterPublisher writing the tweets to w instead of posting them, along
+// with their length as counted by twitter and whether they fit in a tweet
+func NewTwitterPreviewPublisher(config TwitterConfig, w io.Writer) *TwitterPublisher {
+	return &TwitterPublisher{
+		post: func(status string) error {
+			length := tweet.Length(status)
+			if _, err := fmt.Fprintf(w, "(%d/%d) %s\n", length, tweet.MaxLength, status); err != nil {
+				return err
+			}
+			if length > tweet.MaxLength {
+				_, err := fmt.Fprintf(w, "!! exceeds the twitter limit by %d characters\n", length-tweet.MaxLength)
+				return err
+			}
+			return nil
+		},
+		mentions: config.GetTwitterMentions(),
+	}
+}
+
 // Publish a new ballot as a tweet
 func (t *TwitterPublisher) Publish(ballot *models.Ballot) error {
 	status, err := GetStatusString(ballot)
 	if err != nil {
 		return err
 	}
-	_, _, err = t.client.Statuses.Update(status, nil)
-	if err == nil {
-		log.Printf("(twitter) Published status: %s\n", status)
-	}
-	return err
+	return t.post(status)
 }
 
 // PublishProtoChange a new protocol change message as a tweet
 func (t *TwitterPublisher) PublishProtoChange(proto string) error {
 	status := GetProtocolString(proto)
-	_, _, err := t.client.Statuses.Update(status, nil)
-	if err == nil {
-		log.Printf("(twitter) Published status: %s\n", status)
-	}
-	return err
+	return t.post(status)
 }
 
 // PublishProposalInjection a new proposal injection message as a tweet
 func (t *TwitterPublisher) PublishProposalInjection(proposal *models.Proposal) error {
 	status := GetProposalInjectString(proposal)
-	_, _, err := t.client.Statuses.Update(status, nil)
-	if err == nil {
-		log.Printf("(twitter) Published status: %s\n", status)
-	}
-	return err
+	return t.post(status)
 }
 
 // PublishProposalSummary a new proposal summary message as a tweet
 func (t *TwitterPublisher) PublishProposalSummary(proposal *models.ProposalSummary) error {
 	status := GetProposalSummaryString(proposal)
-	_, _, err := t.client.Statuses.Update(status, nil)
-	if err == nil {
-		log.Printf("(twitter) Published status: %s\n", status)
-	}
-	return err
+	return t.post(status)
 }
 
 // PublishWinningProposalSummary a new winning proposal summary message as a tweet
 func (t *TwitterPublisher) PublishWinningProposalSummary(proposal *models.ProposalSummary) error {
 	status := GetWinningProposalString(proposal)
-	_, _, err := t.client.Statuses.Update(status, nil)
-	if err == nil {
-		log.Printf("(twitter) Published status: %s\n", status)
-	}
-	return err
+	return t.post(status)
 }
 
 // PublishProposalUpvote a new proposal upvote message to twitter
 func (t *TwitterPublisher) PublishProposalUpvote(proposal *models.Proposal) error {
 	status := GetProposalUpvoteString(proposal)
-	_, _, err := t.client.Statuses.Update(status, nil)
-	if err == nil {
-		log.Printf("(twitter) Published status: %s\n", status)
+	return t.post(status)
+}
+
+// PublishNewPeriod a new voting period message as a tweet
+func (t *TwitterPublisher) PublishNewPeriod(period *models.VotingPeriod) error {
+	status, err := GetNewPeriodString(period)
+	if err != nil {
+		return err
+	}
+	return t.post(status)
+}
+
+// PublishActivationCountdown a new activation countdown message as a tweet
+func (t *TwitterPublisher) PublishActivationCountdown(countdown *models.ActivationCountdown) error {
+	status, err := GetActivationCountdownString(countdown)
+	if err != nil {
+		return err
+	}
+	return t.post(status)
+}
+
+// PublishPeriodTransition a new period transition message as a tweet
+func (t *TwitterPublisher) PublishPeriodTransition(transition *models.PeriodTransition) error {
+	status, err := GetPeriodTransitionString(transition)
+	if err != nil {
+		return err
+	}
+	return t.post(status)
+}
+
+// PublishFinalReport the final results of a ballot period as a tweet
+func (t *TwitterPublisher) PublishFinalReport(report *models.FinalReport) error {
+	status, err := GetFinalReportString(report)
+	if err != nil {
+		return err
+	}
+	return t.post(status)
+}
+
+// PublishBallotDigest a new ballot digest message as a tweet
+func (t *TwitterPublisher) PublishBallotDigest(digest *models.BallotDigest) error {
+	status, err := GetBallotDigestString(digest)
+	if err != nil {
+		return err
+	}
+	return t.post(status)
+}
+
+// PublishMilestone a new vote milestone message as a tweet
+func (t *TwitterPublisher) PublishMilestone(milestone *models.Milestone) error {
+	status, err := GetMilestoneString(milestone)
+	if err != nil {
+		return err
+	}
+	return t.post(status)
+}
+
+// PublishNonVoterReport a new non voter report message as a tweet
+func (t *TwitterPublisher) PublishNonVoterReport(report *models.NonVoterReport) error {
+	status, err := GetNonVoterReportString(report, t.mentions)
+	if err != nil {
+		return err
+	}
+	return t.post(status)
+}
+
+// PublishProposalLeaderboard a new proposal leaderboard message as a tweet
+func (t *TwitterPublisher) PublishProposalLeaderboard(leaderboard *models.ProposalLeaderboard) error {
+	status, err := GetProposalLeaderboardString(leaderboard)
+	if err != nil {
+		return err
+	}
+	return t.post(status)
+}
+
+// PublishAnnouncement a new announcement message as a tweet
+func (t *TwitterPublisher) PublishAnnouncement(announcement *models.Announcement) error {
+	status, err := GetAnnouncementString(announcement)
+	if err != nil {
+		return err
 	}
-	return err
+	return t.post(status)
 }
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/ecadlabs/tezos-bot/admin"
+	"github.com/ecadlabs/tezos-bot/api"
+	"github.com/ecadlabs/tezos-bot/config"
+	"github.com/ecadlabs/tezos-bot/health"
+	"github.com/ecadlabs/tezos-bot/listen"
+	"github.com/ecadlabs/tezos-bot/publish"
+	"github.com/ecadlabs/tezos-bot/service"
+	"github.com/ecadlabs/tezos-bot/state"
+	"github.com/ecadlabs/tezos-bot/store"
+	"github.com/ecadlabs/tezos-bot/stream"
+)
+
+func runRun(args []string) int {
+	fs := newFlagSet("run", "[flags]", "Monitor the chain and publish the detected events.")
+	configFile := configFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	c, err := loadConfig(*configFile)
+	if err != nil {
+		log.Printf(err.Error())
+		return 1
+	}
+	return runBot(c, modePublish)
+}
+
+func runBackfill(args []string) int {
+	fs := newFlagSet("backfill", "[flags]", "Replay a range of levels and publish the detected events, then exit.")
+	configFile := configFlag(fs)
+	from := fs.Int("from", -1, "First level to replay, defaults to history_starting_block.")
+	to := fs.Int("to", -1, "Last level to replay, defaults to history_ending_block or the current head.")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	c, err := loadHistoryConfig(*configFile, *from, *to)
+	if err != nil {
+		log.Printf(err.Error())
+		return 1
+	}
+	return runBot(c, modePublish)
+}
+
+func runReplay(args []string) int {
+	fs := newFlagSet("replay", "[flags]", "Re-run the detectors over a range of levels and print the events to stdout instead of publishing,\nrecording or serving them. The listener state is kept in memory.")
+	configFile := configFlag(fs)
+	from := fs.Int("from", -1, "First level to replay, defaults to history_starting_block.")
+	to := fs.Int("to", -1, "Last level to replay, defaults to history_ending_block or the current head.")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	c, err := loadHistoryConfig(*configFile, *from, *to)
+	if err != nil {
+		log.Printf(err.Error())
+		return 1
+	}
+	return runBot(c, modeReplay)
+}
+
+// loadHistoryConfig loads the config of a history replay, negative levels keep the configured ones
+func loadHistoryConfig(name string, from, to int) (config.Config, error) {
+	c, err := loadConfig(name)
+	if err != nil {
+		return c, err
+	}
+	c.History = true
+	if from >= 0 {
+		c.HistoryStartingBlock = from
+	}
+	if to >= 0 {
+		c.HistoryEndingBlock = to
+	}
+	return c, nil
+}
+
+// botMode selects what is done with the events detected by a bot run
+type botMode int
+
+const (
+	// modePublish publishes, records and serves the events
+	modePublish botMode = iota
+	// modeReplay prints the events with the debug publisher
+	modeReplay
+	// modePreview only sends the events to the sinks
+	modePreview
+)
+
+// runBot runs the listener until it stops and returns the exit code. Unless the events are published nothing
+// is recorded, served or persisted
+func runBot(c config.Config, mode botMode, sinks ...service.EventSink) int {
+	dryRun := mode != modePublish
+	status := health.NewStatus()
+
+	var events service.EventStore
+	var recent admin.EventReader
+	var hub *stream.Hub
+	var index service.EventSink
+	var stateStore listen.StateStore = state.NewMemoryStore()
+
+	if !dryRun {
+		health.NewServer(c, status).Start()
+
+		var reader api.EventReader
+		if path := c.GetEventStore(); path != "" {
+			eventStore, err := store.Open(path)
+			if err != nil {
+				log.Printf(err.Error())
+				return 1
+			}
+			defer eventStore.Close()
+			events = eventStore
+			reader = eventStore
+			recent = eventStore
+		}
+
+		// The milestones, leaderboard and digest state hold the periods the live bot is in, a backfill of past
+		// periods keeps its own state in memory rather than overwriting them
+		if !c.IsHistory() {
+			stateStore = state.NewFileStore(c.GetStateFile())
+		}
+
+		if c.GetAPIAddr() != "" {
+			apiServer := api.NewServer(c, reader)
+			if reader != nil {
+				index = apiServer
+			}
+
+			if size := c.GetStreamBuffer(); size > 0 {
+				var err error
+				if hub, err = stream.NewHub(size, c.GetStreamFile()); err != nil {
+					log.Printf(err.Error())
+					return 1
+				}
+				defer hub.Close()
+				apiServer.Handle("/api/stream", http.HandlerFunc(hub.ServeSSE))
+				apiServer.Handle("/api/ws", http.HandlerFunc(hub.ServeWebSocket))
+			}
+
+			apiServer.Start()
+		}
+	}
+
+	l, err := listen.NewTezosListener(c, status, stateStore)
+
+	if err != nil {
+		log.Printf(err.Error())
+		return 1
+	}
+
+	var p service.VotePublisher
+	publisherName := ""
+
+	switch mode {
+	case modeReplay:
+		p, publisherName = &publish.DebugPublisher{Mentions: c.GetTwitterMentions()}, "debug"
+	case modePublish:
+		if p, publisherName, err = newPublisher(c, status); err != nil {
+			log.Printf(err.Error())
+			return 1
+		}
+	}
+
+	s := service.New(l, p, publisherName, events)
+
+	// Scheduled announcements are only posted live, not by backfills, replays or previews
+	if mode == modePublish && !c.IsHistory() {
+		scheduler, err := newScheduler(c, stateStore)
+		if err != nil {
+			log.Printf(err.Error())
+			return 1
+		}
+		if scheduler != nil {
+			s.SetScheduler(scheduler)
+		}
+	}
+	if index != nil {
+		s.AddSink(index)
+	}
+	if hub != nil {
+		s.AddSink(hub)
+	}
+	for _, sink := range sinks {
+		s.AddSink(sink)
+	}
+
+	if !dryRun && c.GetAdminAddr() != "" {
+		admin.NewServer(c, status, recent, []*service.Control{s.Control()}, twitterPreviewer{c}).Start()
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sig
+		log.Println("Stopping bot...")
+		s.Stop()
+	}()
+
+	log.Println("Bot started...")
+
+	if err := s.Start(); err != nil {
+		log.Printf("Bot stopped because of error: %s\n", err.Error())
+		return 1
+	}
+
+	log.Println("Bot stopped")
+	return 0
+}
+
+// newPublisher returns the twitter publisher when its credentials are configured, the debug publisher otherwise
+func newPublisher(c config.Config, status *health.Status) (service.VotePublisher, string, error) {
+	if c.GetTwitterAccessToken() == "" {
+		log.Printf("Twitter access token not configured posting vote to stdout\n")
+		return &publish.DebugPublisher{Mentions: c.GetTwitterMentions()}, "debug", nil
+	}
+
+	p, err := publish.NewTwitterPublisher(c)
+	status.SetPublisher("twitter", err == nil)
+	if err != nil {
+		return nil, "", err
+	}
+	return p, "twitter", nil
+}
+
+// newScheduler returns the scheduler of the configured announcements, nil when none is configured
+func newScheduler(c config.Config, store service.StateStore) (*service.Scheduler, error) {
+	schedules := c.GetSchedules()
+	if len(schedules) == 0 {
+		return nil, nil
+	}
+
+	rules := []service.ScheduleRule{}
+	for _, sc := range schedules {
+		rules = append(rules, service.ScheduleRule{
+			Name:                  sc.Name,
+			Template:              sc.Template,
+			Vars:                  sc.Vars,
+			BlocksBeforePeriodEnd: sc.BlocksBeforePeriodEnd,
+			PeriodKinds:           sc.PeriodKinds,
+			Activation:            sc.Activation,
+			Level:                 sc.Level,
+			Cron:                  sc.Cron,
+		})
+	}
+	return service.NewScheduler(rules, store)
+}
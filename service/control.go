@@ -0,0 +1,100 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+// controlErrors is the number of publication errors kept per publisher
+const controlErrors = 10
+
+// PublicationError is a publication rejected by a publisher
+type PublicationError struct {
+	Kind  string    `json:"kind"`
+	Error string    `json:"error"`
+	At    time.Time `json:"at"`
+}
+
+// ControlReport is a point in time snapshot of a Control
+type ControlReport struct {
+	Name      string `json:"name"`
+	Paused    bool   `json:"paused"`
+	Published int    `json:"published"`
+	Failed    int    `json:"failed"`
+	// Skipped counts the events not published while the publisher was paused
+	Skipped int `json:"skipped"`
+	// LastErrors are the last publication errors, newest first
+	LastErrors []PublicationError `json:"last_errors"`
+}
+
+// Control pauses and resumes the publications of a publisher and keeps track of their outcome
+type Control struct {
+	mu        sync.Mutex
+	name      string
+	paused    bool
+	published int
+	failed    int
+	skipped   int
+	errors    []PublicationError
+}
+
+// NewControl create a new Control of the named publisher
+func NewControl(name string) *Control {
+	return &Control{name: name}
+}
+
+// Name returns the name of the publisher
+func (c *Control) Name() string {
+	return c.name
+}
+
+// SetPaused pauses or resumes the publications, events keep being recorded and sent to the sinks while paused
+func (c *Control) SetPaused(paused bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.paused = paused
+}
+
+// Paused returns true if the publications are paused
+func (c *Control) Paused() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.paused
+}
+
+// Report returns a snapshot of the control
+func (c *Control) Report() ControlReport {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return ControlReport{
+		Name:       c.name,
+		Paused:     c.paused,
+		Published:  c.published,
+		Failed:     c.failed,
+		Skipped:    c.skipped,
+		LastErrors: append([]PublicationError{}, c.errors...),
+	}
+}
+
+// skip counts an event not published because the publisher is paused
+func (c *Control) skip() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.skipped++
+}
+
+// record counts the outcome of a publication, err is nil on success
+func (c *Control) record(kind string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err == nil {
+		c.published++
+		return
+	}
+
+	c.failed++
+	c.errors = append([]PublicationError{{Kind: kind, Error: err.Error(), At: time.Now().UTC()}}, c.errors...)
+	if len(c.errors) > controlErrors {
+		c.errors = c.errors[:controlErrors]
+	}
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCronErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+	}{
+		{name: "too few fields", expr: "0 9 * *"},
+		{name: "too many fields", expr: "0 9 * * * *"},
+		{name: "minute out of range", expr: "60 * * * *"},
+		{name: "hour out of range", expr: "0 24 * * *"},
+		{name: "day of month zero", expr: "0 0 0 * *"},
+		{name: "month out of range", expr: "0 0 1 13 *"},
+		{name: "day of week out of range", expr: "0 0 * * 8"},
+		{name: "reversed range", expr: "30-10 * * * *"},
+		{name: "zero step", expr: "*/0 * * * *"},
+		{name: "not a number", expr: "a * * * *"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseCron(tt.expr); err == nil {
+				t.Errorf("parseCron(%q) succeeded, want an error", tt.expr)
+			}
+		})
+	}
+}
+
+func TestCronMatches(t *testing.T) {
+	// 2024-01-01 is a Monday
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, time.January, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name string
+		expr string
+		t    time.Time
+		want bool
+	}{
+		{name: "every minute", expr: "* * * * *", t: at(1, 13, 37), want: true},
+		{name: "weekly on monday", expr: "0 9 * * 1", t: at(1, 9, 0), want: true},
+		{name: "weekly other minute", expr: "0 9 * * 1", t: at(1, 9, 1), want: false},
+		{name: "weekly other day", expr: "0 9 * * 1", t: at(2, 9, 0), want: false},
+		{name: "sunday as 7", expr: "0 0 * * 7", t: at(7, 0, 0), want: true},
+		{name: "sunday as 0", expr: "0 0 * * 0", t: at(7, 0, 0), want: true},
+		{name: "step", expr: "*/15 * * * *", t: at(1, 0, 45), want: true},
+		{name: "step miss", expr: "*/15 * * * *", t: at(1, 0, 50), want: false},
+		{name: "range with step", expr: "0-30/10 * * * *", t: at(1, 0, 20), want: true},
+		{name: "range with step past the range", expr: "0-30/10 * * * *", t: at(1, 0, 40), want: false},
+		{name: "value with step", expr: "5/20 * * * *", t: at(1, 0, 45), want: true},
+		{name: "list", expr: "0 8,20 * * *", t: at(1, 20, 0), want: true},
+		{name: "day of month", expr: "0 0 15 * *", t: at(15, 0, 0), want: true},
+		{name: "month", expr: "0 0 * 2 *", t: at(1, 0, 0), want: false},
+		// When both days are restricted either one matches
+		{name: "day of month or week, by month day", expr: "0 0 15 * 5", t: at(15, 0, 0), want: true},
+		{name: "day of month or week, by week day", expr: "0 0 15 * 5", t: at(5, 0, 0), want: true},
+		{name: "day of month or week, neither", expr: "0 0 15 * 5", t: at(6, 0, 0), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := parseCron(tt.expr)
+			if err != nil {
+				t.Fatalf("parseCron(%q): %s", tt.expr, err)
+			}
+			if got := c.matches(tt.t); got != tt.want {
+				t.Errorf("%q matches %s = %v, want %v", tt.expr, tt.t.Format(time.RFC1123), got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/ecadlabs/tezos-bot/models"
+)
+
+// Dispatch decodes an event recorded with its kind and publishes it with the matching method of the publisher
+func Dispatch(p VotePublisher, kind string, payload json.RawMessage) error {
+	switch kind {
+	case models.EventBallot:
+		var v models.Ballot
+		if err := json.Unmarshal(payload, &v); err != nil {
+			return err
+		}
+		return p.Publish(&v)
+	case models.EventProtocol:
+		var v string
+		if err := json.Unmarshal(payload, &v); err != nil {
+			return err
+		}
+		return p.PublishProtoChange(v)
+	case models.EventProposalInjection:
+		var v models.Proposal
+		if err := json.Unmarshal(payload, &v); err != nil {
+			return err
+		}
+		return p.PublishProposalInjection(&v)
+	case models.EventProposalUpvote:
+		var v models.Proposal
+		if err := json.Unmarshal(payload, &v); err != nil {
+			return err
+		}
+		return p.PublishProposalUpvote(&v)
+	case models.EventProposalSummary:
+		var v models.ProposalSummary
+		if err := json.Unmarshal(payload, &v); err != nil {
+			return err
+		}
+		return p.PublishProposalSummary(&v)
+	case models.EventWinningProposal:
+		var v models.ProposalSummary
+		if err := json.Unmarshal(payload, &v); err != nil {
+			return err
+		}
+		return p.PublishWinningProposalSummary(&v)
+	case models.EventVotingPeriod:
+		var v models.VotingPeriod
+		if err := json.Unmarshal(payload, &v); err != nil {
+			return err
+		}
+		return p.PublishNewPeriod(&v)
+	case models.EventActivationCountdown:
+		var v models.ActivationCountdown
+		if err := json.Unmarshal(payload, &v); err != nil {
+			return err
+		}
+		return p.PublishActivationCountdown(&v)
+	case models.EventPeriodTransition:
+		var v models.PeriodTransition
+		if err := json.Unmarshal(payload, &v); err != nil {
+			return err
+		}
+		return p.PublishPeriodTransition(&v)
+	case models.EventFinalReport:
+		var v models.FinalReport
+		if err := json.Unmarshal(payload, &v); err != nil {
+			return err
+		}
+		return p.PublishFinalReport(&v)
+	case models.EventBallotDigest:
+		var v models.BallotDigest
+		if err := json.Unmarshal(payload, &v); err != nil {
+			return err
+		}
+		return p.PublishBallotDigest(&v)
+	case models.EventMilestone:
+		var v models.Milestone
+		if err := json.Unmarshal(payload, &v); err != nil {
+			return err
+		}
+		return p.PublishMilestone(&v)
+	case models.EventNonVoterReport:
+		var v models.NonVoterReport
+		if err := json.Unmarshal(payload, &v); err != nil {
+			return err
+		}
+		return p.PublishNonVoterReport(&v)
+	case models.EventProposalLeaderboard:
+		var v models.ProposalLeaderboard
+		if err := json.Unmarshal(payload, &v); err != nil {
+			return err
+		}
+		return p.PublishProposalLeaderboard(&v)
+	case models.EventAnnouncement:
+		var v models.Announcement
+		if err := json.Unmarshal(payload, &v); err != nil {
+			return err
+		}
+		return p.PublishAnnouncement(&v)
+	}
+	return fmt.Errorf("unknown event kind %q", kind)
+}
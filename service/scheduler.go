@@ -0,0 +1,197 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/ecadlabs/tezos-bot/models"
+)
+
+const schedulerStateKey = "schedules"
+
+// scheduleLateBlocks is how many blocks past its level a trigger still fires, so that the heads missed while
+// reconnecting do not lose it. Later, e.g. after a restart, the trigger is skipped rather than posted late
+const scheduleLateBlocks = 5
+
+// StateStore interface for required methods of a store persisting the scheduler state across restarts
+type StateStore interface {
+	Load(key string, v interface{}) (bool, error)
+	Save(key string, v interface{}) error
+}
+
+// ScheduleRule is an announcement rendered from a template when one of its triggers is reached, exactly one trigger is set
+type ScheduleRule struct {
+	Name     string
+	Template string
+	Vars     map[string]string
+	// BlocksBeforePeriodEnd fires once per voting period that many blocks before its end
+	BlocksBeforePeriodEnd int
+	// PeriodKinds restricts BlocksBeforePeriodEnd to these kinds of voting period, empty matches them all
+	PeriodKinds []string
+	// Activation fires on the first block after an adoption period, when the adopted proposal is activated
+	Activation bool
+	// Level fires once when the chain reaches the level
+	Level int
+	// Cron fires on the wall clock, a five fields cron expression evaluated in UTC
+	Cron string
+}
+
+type scheduleRule struct {
+	ScheduleRule
+	cron  *cronSchedule
+	kinds map[string]bool
+}
+
+// schedulerState is persisted so that no rule fires twice for the same trigger across restarts
+type schedulerState struct {
+	// Fired is the last trigger each rule fired for, by rule name
+	Fired map[string]string
+	// Head is the last block seen, an activation is detected from the adoption period it ended
+	Head *models.Head
+}
+
+// Scheduler evaluates the scheduled announcements against the heads of the chain and the wall clock
+type Scheduler struct {
+	rules []*scheduleRule
+	store StateStore
+	state schedulerState
+}
+
+// NewScheduler returns a scheduler of the given rules, the triggers already fired are loaded from store
+func NewScheduler(rules []ScheduleRule, store StateStore) (*Scheduler, error) {
+	s := &Scheduler{store: store}
+	for _, r := range rules {
+		rule := &scheduleRule{ScheduleRule: r, kinds: make(map[string]bool)}
+		if r.Cron != "" {
+			cron, err := parseCron(r.Cron)
+			if err != nil {
+				return nil, fmt.Errorf("schedule %q: %s", r.Name, err.Error())
+			}
+			rule.cron = cron
+		}
+		for _, kind := range r.PeriodKinds {
+			rule.kinds[kind] = true
+		}
+		s.rules = append(s.rules, rule)
+	}
+
+	if _, err := store.Load(schedulerStateKey, &s.state); err != nil {
+		return nil, err
+	}
+	if s.state.Fired == nil {
+		s.state.Fired = make(map[string]string)
+	}
+	return s, nil
+}
+
+// Head evaluates the level based rules against a new head and returns the announcements due
+func (s *Scheduler) Head(head *models.Head) []*models.Announcement {
+	previous := s.state.Head
+	s.state.Head = head
+
+	due := []*models.Announcement{}
+	for _, rule := range s.rules {
+		var trigger string
+		vars := headVars(head)
+
+		switch {
+		case rule.BlocksBeforePeriodEnd > 0:
+			p := head.Period
+			level := p.EndLevel - rule.BlocksBeforePeriodEnd
+			if level < p.StartLevel {
+				level = p.StartLevel
+			}
+			if reached(head, level) && (len(rule.kinds) == 0 || rule.kinds[p.Kind]) {
+				trigger = "period " + strconv.Itoa(p.Index)
+			}
+		case rule.Activation:
+			if previous != nil && previous.Period.Kind == "adoption" && reached(head, previous.Period.EndLevel+1) {
+				trigger = "period " + strconv.Itoa(previous.Period.Index)
+				vars["proposal"] = previous.Period.ProposalHash
+				vars["activation_level"] = strconv.Itoa(previous.Period.EndLevel + 1)
+			}
+		case rule.Level > 0:
+			if reached(head, rule.Level) {
+				trigger = "level " + strconv.Itoa(rule.Level)
+			}
+		}
+
+		if trigger == "" || s.state.Fired[rule.Name] == trigger {
+			continue
+		}
+		s.state.Fired[rule.Name] = trigger
+		due = append(due, rule.announcement(head.BlockRef, vars))
+	}
+
+	// The state is saved before publishing, a crash may lose an announcement but never posts it twice
+	if len(due) != 0 || previous == nil || previous.Period.Index != head.Period.Index {
+		s.save()
+	}
+	return due
+}
+
+// Tick evaluates the wall clock rules and returns the announcements due at the minute of now
+func (s *Scheduler) Tick(now time.Time) []*models.Announcement {
+	minute := now.UTC().Truncate(time.Minute)
+	trigger := minute.Format(time.RFC3339)
+
+	due := []*models.Announcement{}
+	for _, rule := range s.rules {
+		if rule.cron == nil || !rule.cron.matches(minute) || s.state.Fired[rule.Name] == trigger {
+			continue
+		}
+		s.state.Fired[rule.Name] = trigger
+
+		vars := make(map[string]string)
+		if s.state.Head != nil {
+			vars = headVars(s.state.Head)
+		}
+		due = append(due, rule.announcement(models.BlockRef{Timestamp: minute}, vars))
+	}
+
+	if len(due) != 0 {
+		s.save()
+	}
+	return due
+}
+
+// reached returns true if head is at level or no more than scheduleLateBlocks past it
+func reached(head *models.Head, level int) bool {
+	return head.Level >= level && head.Level <= level+scheduleLateBlocks
+}
+
+func (s *Scheduler) save() {
+	if err := s.store.Save(schedulerStateKey, &s.state); err != nil {
+		log.Printf("Scheduler state was not able to be saved due to error: %s", err.Error())
+	}
+}
+
+// announcement returns the announcement of the rule, its own variables take precedence over the ones of the chain
+func (r *scheduleRule) announcement(ref models.BlockRef, vars map[string]string) *models.Announcement {
+	for name, value := range r.Vars {
+		vars[name] = value
+	}
+	return &models.Announcement{
+		BlockRef: ref,
+		Template: r.Template,
+		Vars:     vars,
+		Schedule: r.Name,
+	}
+}
+
+// headVars returns the template variables describing the state of the chain at head
+func headVars(head *models.Head) map[string]string {
+	p := head.Period
+	return map[string]string{
+		"level":               strconv.Itoa(head.Level),
+		"period_index":        strconv.Itoa(p.Index),
+		"period_kind":         p.Kind,
+		"period_start":        strconv.Itoa(p.StartLevel),
+		"period_end":          strconv.Itoa(p.EndLevel),
+		"period_end_estimate": p.EstimatedEnd.UTC().Format("2006-01-02 15:04 UTC"),
+		"blocks_left":         strconv.Itoa(p.EndLevel - head.Level),
+		"proposal":            p.ProposalHash,
+	}
+}
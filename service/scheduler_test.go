@@ -0,0 +1,102 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ecadlabs/tezos-bot/models"
+	"github.com/ecadlabs/tezos-bot/state"
+)
+
+func testHead(level int, kind string, index, start, end int) *models.Head {
+	h := &models.Head{Period: &models.VotingPeriod{Index: index, Kind: kind, StartLevel: start, EndLevel: end, ProposalHash: "PtTest"}}
+	h.Level = level
+	return h
+}
+
+func schedules(announcements []*models.Announcement) []string {
+	names := []string{}
+	for _, a := range announcements {
+		names = append(names, a.Schedule)
+	}
+	return names
+}
+
+func TestSchedulerHead(t *testing.T) {
+	rules := []ScheduleRule{
+		{Name: "ending", Template: "ending", BlocksBeforePeriodEnd: 100, PeriodKinds: []string{"adoption", "proposal"}},
+		{Name: "activation", Template: "activation", Activation: true},
+		{Name: "level", Template: "level", Level: 950},
+	}
+	store := state.NewMemoryStore()
+	s, err := NewScheduler(rules, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		name string
+		head *models.Head
+		// reload recreates the scheduler from the store before the head, as a restart does
+		reload bool
+		want   []string
+	}{
+		{name: "before the window", head: testHead(899, "adoption", 1, 1, 1000), want: []string{}},
+		{name: "window reached late", head: testHead(903, "adoption", 1, 1, 1000), want: []string{"ending"}},
+		{name: "window fired once", head: testHead(904, "adoption", 1, 1, 1000), want: []string{}},
+		{name: "not again after a restart", head: testHead(905, "adoption", 1, 1, 1000), reload: true, want: []string{}},
+		{name: "level", head: testHead(950, "adoption", 1, 1, 1000), want: []string{"level"}},
+		{name: "level fired once", head: testHead(951, "adoption", 1, 1, 1000), reload: true, want: []string{}},
+		{name: "last adoption block", head: testHead(1000, "adoption", 1, 1, 1000), want: []string{}},
+		{name: "activation", head: testHead(1001, "proposal", 2, 1001, 2000), want: []string{"activation"}},
+		{name: "activation fired once", head: testHead(1002, "proposal", 2, 1001, 2000), reload: true, want: []string{}},
+		// The window of period 2 starts at 1900, the bot was stopped through it
+		{name: "window passed while stopped", head: testHead(1950, "proposal", 2, 1001, 2000), reload: true, want: []string{}},
+	}
+
+	for _, step := range steps {
+		if step.reload {
+			if s, err = NewScheduler(rules, store); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if got := schedules(s.Head(step.head)); !reflect.DeepEqual(got, step.want) {
+			t.Errorf("%s: Head(%d) fired %v, want %v", step.name, step.head.Level, got, step.want)
+		}
+	}
+}
+
+func TestSchedulerTick(t *testing.T) {
+	rules := []ScheduleRule{{Name: "hourly", Template: "hourly", Cron: "0 * * * *"}}
+	store := state.NewMemoryStore()
+	s, err := NewScheduler(rules, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2024, time.January, 1, 9, 0, 0, 0, time.UTC)
+	steps := []struct {
+		name   string
+		now    time.Time
+		reload bool
+		want   []string
+	}{
+		{name: "scheduled minute", now: start, want: []string{"hourly"}},
+		{name: "next tick of the same minute", now: start.Add(schedulerTick), want: []string{}},
+		{name: "same minute after a restart", now: start.Add(2 * schedulerTick), reload: true, want: []string{}},
+		{name: "next minute", now: start.Add(time.Minute), want: []string{}},
+		{name: "next hour", now: start.Add(time.Hour), want: []string{"hourly"}},
+	}
+
+	for _, step := range steps {
+		if step.reload {
+			if s, err = NewScheduler(rules, store); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if got := schedules(s.Tick(step.now)); !reflect.DeepEqual(got, step.want) {
+			t.Errorf("%s: Tick(%s) fired %v, want %v", step.name, step.now.Format(time.RFC3339), got, step.want)
+		}
+	}
+}
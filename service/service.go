@@ -2,20 +2,30 @@ package service
 
 import (
 	"log"
+	"time"
 
 	"github.com/ecadlabs/tezos-bot/models"
 )
 
 // ChainListener interface for required methods of a chain listener
 type ChainListener interface {
-	Start()
+	Start() error
 	Stop()
 	GetNewVotes() chan *models.Ballot
-	GetNewProto() chan string
+	GetNewProto() chan *models.ProtocolChange
 	GetNewProposal() chan *models.Proposal
 	GetProposalUpvotes() chan *models.Proposal
 	GetProposalSummary() chan *models.ProposalSummary
 	GetWinningProposal() chan *models.ProposalSummary
+	GetNewPeriod() chan *models.VotingPeriod
+	GetActivationCountdown() chan *models.ActivationCountdown
+	GetPeriodTransition() chan *models.PeriodTransition
+	GetFinalReport() chan *models.FinalReport
+	GetBallotDigest() chan *models.BallotDigest
+	GetMilestone() chan *models.Milestone
+	GetNonVoterReport() chan *models.NonVoterReport
+	GetProposalLeaderboard() chan *models.ProposalLeaderboard
+	GetNewHead() chan *models.Head
 }
 
 // VotePublisher interface for required methods of a vote publisher
@@ -26,63 +36,212 @@ type VotePublisher interface {
 	PublishProposalInjection(proto *models.Proposal) error
 	PublishProposalSummary(proposal *models.ProposalSummary) error
 	PublishWinningProposalSummary(proposal *models.ProposalSummary) error
+	PublishNewPeriod(period *models.VotingPeriod) error
+	PublishActivationCountdown(countdown *models.ActivationCountdown) error
+	PublishPeriodTransition(transition *models.PeriodTransition) error
+	PublishFinalReport(report *models.FinalReport) error
+	PublishBallotDigest(digest *models.BallotDigest) error
+	PublishMilestone(milestone *models.Milestone) error
+	PublishNonVoterReport(report *models.NonVoterReport) error
+	PublishProposalLeaderboard(leaderboard *models.ProposalLeaderboard) error
+	PublishAnnouncement(announcement *models.Announcement) error
 }
 
+// EventStore interface for required methods of an event store
+type EventStore interface {
+	Record(kind string, ref models.BlockRef, payload interface{}) (uint64, error)
+	SetPublication(id uint64, publisher string, err error) error
+}
+
+// EventSink interface for required methods of a sink receiving every dispatched event
+type EventSink interface {
+	Send(kind string, ref models.BlockRef, event interface{}) error
+}
+
+// schedulerTick is how often the wall clock schedules are evaluated, often enough to see every minute
+const schedulerTick = 20 * time.Second
+
 // Service main service that listen for new vote on a chain and publish them
 type Service struct {
 	chainListener ChainListener
 	votePublisher VotePublisher
-	signals       chan bool
+	publisherName string
+	events        EventStore
+	sinks         []EventSink
+	scheduler     *Scheduler
+	control       *Control
 }
 
-// New Create a new service
-func New(chainListener ChainListener, votePublisher VotePublisher) *Service {
+// New Create a new service, events may be nil to not record the events and votePublisher may be nil to only
+// send the events to the sinks. chainListener may be nil when the service is only used to announce
+func New(chainListener ChainListener, votePublisher VotePublisher, publisherName string, events EventStore) *Service {
 	return &Service{
 		chainListener: chainListener,
 		votePublisher: votePublisher,
-		signals:       make(chan bool),
+		publisherName: publisherName,
+		events:        events,
+		control:       NewControl(publisherName),
 	}
 }
 
-// Start a the service
-func (s *Service) Start() {
+// Control returns the control pausing and resuming the vote publisher
+func (s *Service) Control() *Control {
+	return s.control
+}
+
+// AddSink registers a sink receiving every event dispatched alongside the vote publisher, it must be called before Start
+func (s *Service) AddSink(sink EventSink) {
+	s.sinks = append(s.sinks, sink)
+}
+
+// SetScheduler registers the scheduler posting the scheduled announcements, it must be called before Start
+func (s *Service) SetScheduler(scheduler *Scheduler) {
+	s.scheduler = scheduler
+}
+
+// Start a the service, it blocks until the chain listener stops and the events it emitted are published, and
+// returns its error
+func (s *Service) Start() error {
+	done := make(chan struct{})
+	// stopped is closed once the event being published, if any, is done
+	stopped := make(chan struct{})
+	var tick <-chan time.Time
+	if s.scheduler != nil {
+		ticker := time.NewTicker(schedulerTick)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
 	go func() {
+		defer close(stopped)
 		for {
 			select {
 			case vote := <-s.chainListener.GetNewVotes():
-				if err := s.votePublisher.Publish(vote); err != nil {
-					log.Printf("%v was not able to be sent due to error: %s", vote, err.Error())
-				}
-			case proto := <-s.chainListener.GetNewProto():
-				if err := s.votePublisher.PublishProtoChange(proto); err != nil {
-					log.Printf("%v was not able to be sent due to error: %s", proto, err.Error())
-				}
+				s.publish(models.EventBallot, vote.BlockRef, vote, func() error {
+					return s.votePublisher.Publish(vote)
+				})
+			case change := <-s.chainListener.GetNewProto():
+				// The protocol hash alone is recorded, as it always was
+				s.publish(models.EventProtocol, change.BlockRef, change.Protocol, func() error {
+					return s.votePublisher.PublishProtoChange(change.Protocol)
+				})
 			case proposal := <-s.chainListener.GetNewProposal():
-				if err := s.votePublisher.PublishProposalInjection(proposal); err != nil {
-					log.Printf("%v was not able to be sent due to error: %s", *proposal, err.Error())
-				}
+				s.publish(models.EventProposalInjection, proposal.BlockRef, proposal, func() error {
+					return s.votePublisher.PublishProposalInjection(proposal)
+				})
 			case proposal := <-s.chainListener.GetProposalUpvotes():
-				if err := s.votePublisher.PublishProposalUpvote(proposal); err != nil {
-					log.Printf("%v was not able to be sent due to error: %s", *proposal, err.Error())
-				}
+				s.publish(models.EventProposalUpvote, proposal.BlockRef, proposal, func() error {
+					return s.votePublisher.PublishProposalUpvote(proposal)
+				})
 			case summary := <-s.chainListener.GetProposalSummary():
-				if err := s.votePublisher.PublishProposalSummary(summary); err != nil {
-					log.Printf("%v was not able to be sent due to error: %s", summary, err.Error())
-				}
+				s.publish(models.EventProposalSummary, summary.BlockRef, summary, func() error {
+					return s.votePublisher.PublishProposalSummary(summary)
+				})
 			case winning := <-s.chainListener.GetWinningProposal():
-				if err := s.votePublisher.PublishWinningProposalSummary(winning); err != nil {
-					log.Printf("%v was not able to be sent due to error: %s", winning, err.Error())
+				s.publish(models.EventWinningProposal, winning.BlockRef, winning, func() error {
+					return s.votePublisher.PublishWinningProposalSummary(winning)
+				})
+			case period := <-s.chainListener.GetNewPeriod():
+				s.publish(models.EventVotingPeriod, period.BlockRef, period, func() error {
+					return s.votePublisher.PublishNewPeriod(period)
+				})
+			case countdown := <-s.chainListener.GetActivationCountdown():
+				s.publish(models.EventActivationCountdown, countdown.BlockRef, countdown, func() error {
+					return s.votePublisher.PublishActivationCountdown(countdown)
+				})
+			case transition := <-s.chainListener.GetPeriodTransition():
+				s.publish(models.EventPeriodTransition, transition.BlockRef, transition, func() error {
+					return s.votePublisher.PublishPeriodTransition(transition)
+				})
+			case report := <-s.chainListener.GetFinalReport():
+				s.publish(models.EventFinalReport, report.BlockRef, report, func() error {
+					return s.votePublisher.PublishFinalReport(report)
+				})
+			case digest := <-s.chainListener.GetBallotDigest():
+				s.publish(models.EventBallotDigest, digest.BlockRef, digest, func() error {
+					return s.votePublisher.PublishBallotDigest(digest)
+				})
+			case milestone := <-s.chainListener.GetMilestone():
+				s.publish(models.EventMilestone, milestone.BlockRef, milestone, func() error {
+					return s.votePublisher.PublishMilestone(milestone)
+				})
+			case report := <-s.chainListener.GetNonVoterReport():
+				s.publish(models.EventNonVoterReport, report.BlockRef, report, func() error {
+					return s.votePublisher.PublishNonVoterReport(report)
+				})
+			case leaderboard := <-s.chainListener.GetProposalLeaderboard():
+				s.publish(models.EventProposalLeaderboard, leaderboard.BlockRef, leaderboard, func() error {
+					return s.votePublisher.PublishProposalLeaderboard(leaderboard)
+				})
+			case head := <-s.chainListener.GetNewHead():
+				if s.scheduler != nil {
+					for _, announcement := range s.scheduler.Head(head) {
+						s.Announce(announcement)
+					}
+				}
+			case now := <-tick:
+				for _, announcement := range s.scheduler.Tick(now) {
+					s.Announce(announcement)
 				}
-			case _ = <-s.signals:
-				s.chainListener.Stop()
+			case <-done:
 				return
 			}
 		}
 	}()
-	s.chainListener.Start()
+	// The channels are unbuffered, every event the listener emitted has been received once it returns
+	err := s.chainListener.Start()
+	close(done)
+	<-stopped
+	return err
+}
+
+// Announce records, sends to the sinks and publishes an announcement like the events detected on chain
+func (s *Service) Announce(announcement *models.Announcement) error {
+	return s.publish(models.EventAnnouncement, announcement.BlockRef, announcement, func() error {
+		return s.votePublisher.PublishAnnouncement(announcement)
+	})
+}
+
+// publish records an event, sends it to the sinks, publishes it and records the outcome of the publication
+func (s *Service) publish(kind string, ref models.BlockRef, event interface{}, fn func() error) error {
+	var id uint64
+	if s.events != nil {
+		var err error
+		if id, err = s.events.Record(kind, ref, event); err != nil {
+			log.Printf("%s event was not able to be recorded due to error: %s", kind, err.Error())
+		}
+	}
+
+	for _, sink := range s.sinks {
+		if err := sink.Send(kind, ref, event); err != nil {
+			log.Printf("%s event was not able to be sent to sink due to error: %s", kind, err.Error())
+		}
+	}
+
+	if s.votePublisher == nil {
+		return nil
+	}
+
+	if s.control.Paused() {
+		log.Printf("%s event was not published, the %s publisher is paused", kind, s.publisherName)
+		s.control.skip()
+		return nil
+	}
+
+	err := fn()
+	if err != nil {
+		log.Printf("%v was not able to be sent due to error: %s", event, err.Error())
+	}
+	s.control.record(kind, err)
+
+	if id != 0 {
+		if err := s.events.SetPublication(id, s.publisherName, err); err != nil {
+			log.Printf("%s event %d publication was not able to be recorded due to error: %s", kind, id, err.Error())
+		}
+	}
+	return err
 }
 
 // Stop stop the service
 func (s *Service) Stop() {
-	s.signals <- true
+	s.chainListener.Stop()
 }
@@ -0,0 +1,125 @@
+package state
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// FileStore persists named values as a single JSON document so state survives restarts
+type FileStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileStore returns a store backed by the file at path, the file is created on first save
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+// Load decodes the value saved under key into v, it returns false if nothing was saved yet
+func (s *FileStore) Load(key string, v interface{}) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	doc, err := s.read()
+	if err != nil {
+		return false, err
+	}
+
+	raw, ok := doc[key]
+	if !ok {
+		return false, nil
+	}
+	return true, json.Unmarshal(raw, v)
+}
+
+// Save stores v under key, the file is replaced atomically
+func (s *FileStore) Save(key string, v interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	doc, err := s.read()
+	if err != nil {
+		return err
+	}
+
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	doc[key] = raw
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+func (s *FileStore) read() (map[string]json.RawMessage, error) {
+	doc := make(map[string]json.RawMessage)
+	data, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return doc, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return doc, nil
+	}
+	return doc, json.Unmarshal(data, &doc)
+}
+
+// MemoryStore keeps named values in memory, it is used when state must not outlive the process
+type MemoryStore struct {
+	values map[string][]byte
+	mu     sync.Mutex
+}
+
+// NewMemoryStore returns an empty in memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{values: make(map[string][]byte)}
+}
+
+// Load decodes the value saved under key into v, it returns false if nothing was saved yet
+func (s *MemoryStore) Load(key string, v interface{}) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	raw, ok := s.values[key]
+	if !ok {
+		return false, nil
+	}
+	return true, json.Unmarshal(raw, v)
+}
+
+// Save stores v under key
+func (s *MemoryStore) Save(key string, v interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	s.values[key] = raw
+	return nil
+}
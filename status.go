@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const statusTimeout = 10 * time.Second
+
+func runStatus(args []string) int {
+	fs := newFlagSet("status", "[flags]", "Show the health report of a running bot, the exit code is 1 when it is not healthy.")
+	configFile := configFlag(fs)
+	addr := fs.String("addr", "", "Health server address, defaults to health_addr.")
+	ready := fs.Bool("ready", false, "Report readiness rather than liveness.")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	c, err := loadConfig(*configFile)
+	if err != nil {
+		log.Printf(err.Error())
+		return 1
+	}
+
+	target := *addr
+	if target == "" {
+		target = c.GetHealthAddr()
+	}
+	if strings.HasPrefix(target, ":") {
+		target = "localhost" + target
+	}
+	if !strings.Contains(target, "://") {
+		target = "http://" + target
+	}
+	path := "/healthz"
+	if *ready {
+		path = "/readyz"
+	}
+
+	client := &http.Client{Timeout: statusTimeout}
+	resp, err := client.Get(strings.TrimSuffix(target, "/") + path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Bot not reachable: %s\n", err.Error())
+		return 1
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to read the health report: %s\n", err.Error())
+		return 1
+	}
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, body, "", "  "); err != nil {
+		out.Reset()
+		out.Write(body)
+	}
+	fmt.Println(strings.TrimSpace(out.String()))
+
+	if resp.StatusCode != http.StatusOK {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,67 @@
+package store
+
+import (
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	metaBucket   = []byte("meta")
+	eventsBucket = []byte("events")
+	levelsBucket = []byte("levels")
+	versionKey   = []byte("schema_version")
+)
+
+// migrations upgrade the schema one version at a time, the version of a database is the number of migrations
+// applied to it. Never edit a released migration, append a new one instead
+var migrations = []func(tx *bolt.Tx) error{
+	// 1: events keyed by sequence
+	func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(eventsBucket)
+		return err
+	},
+	// 2: index of the events by block level
+	func(tx *bolt.Tx) error {
+		levels, err := tx.CreateBucketIfNotExists(levelsBucket)
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(eventsBucket).ForEach(func(k, v []byte) error {
+			e, err := decodeEvent(v)
+			if err != nil {
+				return err
+			}
+			return levels.Put(levelKey(e.Level, e.ID), nil)
+		})
+	},
+}
+
+// migrate applies the pending migrations, each one in its own transaction
+func migrate(db *bolt.DB) error {
+	for {
+		done := false
+		err := db.Update(func(tx *bolt.Tx) error {
+			meta, err := tx.CreateBucketIfNotExists(metaBucket)
+			if err != nil {
+				return err
+			}
+
+			version := 0
+			if v := meta.Get(versionKey); v != nil {
+				version = int(decodeUint64(v))
+			}
+
+			if version >= len(migrations) {
+				done = true
+				return nil
+			}
+
+			if err := migrations[version](tx); err != nil {
+				return err
+			}
+			return meta.Put(versionKey, encodeUint64(uint64(version+1)))
+		})
+		if err != nil || done {
+			return err
+		}
+	}
+}
@@ -0,0 +1,251 @@
+package store
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ecadlabs/tezos-bot/models"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Publication statuses
+const (
+	StatusPublished = "published"
+	StatusFailed    = "failed"
+)
+
+// Publication is the outcome of publishing an event with a publisher
+type Publication struct {
+	Status string    `json:"status"`
+	Error  string    `json:"error,omitempty"`
+	At     time.Time `json:"at"`
+}
+
+// Event is a governance event detected by the listener
+type Event struct {
+	ID            uint64                 `json:"id"`
+	Kind          string                 `json:"kind"`
+	Level         int                    `json:"level"`
+	BlockHash     string                 `json:"block_hash"`
+	OperationHash string                 `json:"operation_hash,omitempty"`
+	Timestamp     time.Time              `json:"timestamp"`
+	RecordedAt    time.Time              `json:"recorded_at"`
+	Payload       json.RawMessage        `json:"payload"`
+	Publications  map[string]Publication `json:"publications,omitempty"`
+}
+
+// EventStore records the detected events in an embedded bbolt database
+type EventStore struct {
+	db *bolt.DB
+}
+
+// Open opens or creates the database at path and migrates it to the latest schema
+func Open(path string) (*EventStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := migrate(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("migrating %s: %s", path, err)
+	}
+
+	return &EventStore{db: db}, nil
+}
+
+// Close closes the database
+func (s *EventStore) Close() error {
+	return s.db.Close()
+}
+
+// Record stores a new event and returns its id
+func (s *EventStore) Record(kind string, ref models.BlockRef, payload interface{}) (uint64, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return 0, err
+	}
+
+	e := &Event{
+		Kind:          kind,
+		Level:         ref.Level,
+		BlockHash:     ref.BlockHash,
+		OperationHash: ref.OperationHash,
+		Timestamp:     ref.Timestamp,
+		RecordedAt:    time.Now().UTC(),
+		Payload:       raw,
+	}
+
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		events := tx.Bucket(eventsBucket)
+		id, err := events.NextSequence()
+		if err != nil {
+			return err
+		}
+		e.ID = id
+		if err := putEvent(events, e); err != nil {
+			return err
+		}
+		return tx.Bucket(levelsBucket).Put(levelKey(e.Level, e.ID), nil)
+	})
+	return e.ID, err
+}
+
+// SetPublication records the outcome of publishing the event id with publisher, err is nil on success
+func (s *EventStore) SetPublication(id uint64, publisher string, err error) error {
+	p := Publication{Status: StatusPublished, At: time.Now().UTC()}
+	if err != nil {
+		p.Status = StatusFailed
+		p.Error = err.Error()
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		events := tx.Bucket(eventsBucket)
+		e, err := getEvent(events, id)
+		if err != nil {
+			return err
+		}
+		if e.Publications == nil {
+			e.Publications = make(map[string]Publication)
+		}
+		e.Publications[publisher] = p
+		return putEvent(events, e)
+	})
+}
+
+// Get returns the event id, nil if it does not exist
+func (s *EventStore) Get(id uint64) (*Event, error) {
+	var e *Event
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		e, err = getEvent(tx.Bucket(eventsBucket), id)
+		if err == errNotFound {
+			return nil
+		}
+		return err
+	})
+	return e, err
+}
+
+// Filter selects events, zero values match everything
+type Filter struct {
+	Kind string
+	// FromLevel and ToLevel bound the block levels, inclusive
+	FromLevel int
+	ToLevel   int
+	// AfterID only returns events recorded after the event with this id
+	AfterID uint64
+	// Limit caps the number of events returned, 0 is unlimited
+	Limit int
+}
+
+func (f *Filter) match(e *Event) bool {
+	return (f.Kind == "" || e.Kind == f.Kind) &&
+		(f.ToLevel == 0 || e.Level <= f.ToLevel) &&
+		e.Level >= f.FromLevel &&
+		e.ID > f.AfterID
+}
+
+// List returns the events matching the filter ordered by id, or by level and id when a level range is set
+func (s *EventStore) List(f Filter) ([]*Event, error) {
+	result := []*Event{}
+	full := func() bool { return f.Limit > 0 && len(result) >= f.Limit }
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		events := tx.Bucket(eventsBucket)
+
+		if f.FromLevel == 0 && f.ToLevel == 0 {
+			c := events.Cursor()
+			for k, v := c.Seek(encodeUint64(f.AfterID + 1)); k != nil && !full(); k, v = c.Next() {
+				e, err := decodeEvent(v)
+				if err != nil {
+					return err
+				}
+				if f.match(e) {
+					result = append(result, e)
+				}
+			}
+			return nil
+		}
+
+		c := tx.Bucket(levelsBucket).Cursor()
+		for k, _ := c.Seek(levelKey(f.FromLevel, 0)); k != nil && !full(); k, _ = c.Next() {
+			level := int(decodeUint64(k[:8]))
+			if f.ToLevel != 0 && level > f.ToLevel {
+				break
+			}
+			e, err := getEvent(events, decodeUint64(k[8:]))
+			if err != nil {
+				return err
+			}
+			if f.match(e) {
+				result = append(result, e)
+			}
+		}
+		return nil
+	})
+	return result, err
+}
+
+// Recent returns the last limit events recorded, newest first
+func (s *EventStore) Recent(limit int) ([]*Event, error) {
+	result := []*Event{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(eventsBucket).Cursor()
+		for k, v := c.Last(); k != nil && len(result) < limit; k, v = c.Prev() {
+			e, err := decodeEvent(v)
+			if err != nil {
+				return err
+			}
+			result = append(result, e)
+		}
+		return nil
+	})
+	return result, err
+}
+
+var errNotFound = fmt.Errorf("event not found")
+
+func getEvent(events *bolt.Bucket, id uint64) (*Event, error) {
+	v := events.Get(encodeUint64(id))
+	if v == nil {
+		return nil, errNotFound
+	}
+	return decodeEvent(v)
+}
+
+func putEvent(events *bolt.Bucket, e *Event) error {
+	v, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return events.Put(encodeUint64(e.ID), v)
+}
+
+func decodeEvent(v []byte) (*Event, error) {
+	var e Event
+	if err := json.Unmarshal(v, &e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// levelKey orders the level index by level then id
+func levelKey(level int, id uint64) []byte {
+	k := make([]byte, 16)
+	binary.BigEndian.PutUint64(k, uint64(level))
+	binary.BigEndian.PutUint64(k[8:], id)
+	return k
+}
+
+func encodeUint64(v uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	return b
+}
+
+func decodeUint64(b []byte) uint64 {
+	return binary.BigEndian.Uint64(b)
+}
@@ -0,0 +1,147 @@
+package store
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ecadlabs/tezos-bot/models"
+	bolt "go.etcd.io/bbolt"
+)
+
+func tempDB(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(dir, "events.db"), func() { os.RemoveAll(dir) }
+}
+
+func eventIDs(events []*Event) []uint64 {
+	res := []uint64{}
+	for _, e := range events {
+		res = append(res, e.ID)
+	}
+	return res
+}
+
+func TestMigrate(t *testing.T) {
+	path, cleanup := tempDB(t)
+	defer cleanup()
+
+	// A database at version 1 holds the events without the level index
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		meta, err := tx.CreateBucket(metaBucket)
+		if err != nil {
+			return err
+		}
+		if err := meta.Put(versionKey, encodeUint64(1)); err != nil {
+			return err
+		}
+		events, err := tx.CreateBucket(eventsBucket)
+		if err != nil {
+			return err
+		}
+		for _, e := range []*Event{{ID: 1, Kind: "ballot", Level: 30}, {ID: 2, Kind: "ballot", Level: 10}, {ID: 3, Kind: "period", Level: 20}} {
+			if _, err := events.NextSequence(); err != nil {
+				return err
+			}
+			if err := putEvent(events, e); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	var version uint64
+	s.db.View(func(tx *bolt.Tx) error {
+		version = decodeUint64(tx.Bucket(metaBucket).Get(versionKey))
+		return nil
+	})
+	if version != uint64(len(migrations)) {
+		t.Errorf("schema version = %d, want %d", version, len(migrations))
+	}
+
+	// The events recorded before the migration are indexed by level
+	got, err := s.List(Filter{FromLevel: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids, want := eventIDs(got), []uint64{2, 3, 1}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("List by level = %v, want %v", ids, want)
+	}
+
+	// The sequence carries on from the migrated events
+	id, err := s.Record("ballot", models.BlockRef{Level: 40}, struct{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 4 {
+		t.Errorf("Record() = %d, want 4", id)
+	}
+}
+
+func TestList(t *testing.T) {
+	path, cleanup := tempDB(t)
+	defer cleanup()
+
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	// Events are recorded out of level order as a backfill running next to the live listener does
+	for _, e := range []struct {
+		kind  string
+		level int
+	}{{"ballot", 100}, {"period", 50}, {"ballot", 75}, {"ballot", 50}, {"proposal", 120}} {
+		if _, err := s.Record(e.kind, models.BlockRef{Level: e.level}, struct{}{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []uint64
+	}{
+		{name: "everything", filter: Filter{}, want: []uint64{1, 2, 3, 4, 5}},
+		{name: "kind", filter: Filter{Kind: "ballot"}, want: []uint64{1, 3, 4}},
+		{name: "after id", filter: Filter{AfterID: 3}, want: []uint64{4, 5}},
+		{name: "limit", filter: Filter{Limit: 2}, want: []uint64{1, 2}},
+		{name: "from level", filter: Filter{FromLevel: 75}, want: []uint64{3, 1, 5}},
+		{name: "level range", filter: Filter{FromLevel: 50, ToLevel: 75}, want: []uint64{2, 4, 3}},
+		{name: "level range and kind", filter: Filter{FromLevel: 50, ToLevel: 100, Kind: "ballot"}, want: []uint64{4, 3, 1}},
+		{name: "level range and limit", filter: Filter{ToLevel: 100, Limit: 2}, want: []uint64{2, 4}},
+		{name: "nothing", filter: Filter{Kind: "promotion"}, want: []uint64{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.List(tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ids := eventIDs(got); !reflect.DeepEqual(ids, tt.want) {
+				t.Errorf("List(%+v) = %v, want %v", tt.filter, ids, tt.want)
+			}
+		})
+	}
+}
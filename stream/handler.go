@@ -0,0 +1,202 @@
+package stream
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const keepAliveInterval = 30 * time.Second
+
+var upgrader = websocket.Upgrader{
+	// The stream is read only and public, any page is allowed to connect
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// request holds the stream options supplied by a client
+type request struct {
+	kinds  map[string]bool
+	lastID uint64
+	resume bool
+}
+
+// parseRequest reads the kinds to filter on from the kind query parameter, either repeated or comma separated,
+// and the event to resume from, from the Last-Event-ID header or the last_event_id query parameter
+func parseRequest(r *http.Request) (*request, error) {
+	req := &request{}
+
+	for _, v := range r.URL.Query()["kind"] {
+		for _, kind := range strings.Split(v, ",") {
+			if kind = strings.TrimSpace(kind); kind != "" {
+				if req.kinds == nil {
+					req.kinds = make(map[string]bool)
+				}
+				req.kinds[kind] = true
+			}
+		}
+	}
+
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = r.URL.Query().Get("last_event_id")
+	}
+	if lastID != "" {
+		id, err := strconv.ParseUint(lastID, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid last event id %q", lastID)
+		}
+		req.lastID = id
+		req.resume = true
+	}
+
+	return req, nil
+}
+
+func (req *request) match(e *Event) bool {
+	return req.kinds == nil || e.Kind == KindGap || req.kinds[e.Kind]
+}
+
+// ServeSSE streams the events as Server-Sent Events
+func (h *Hub) ServeSSE(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	req, err := parseRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sub, backlog := h.subscribe(req.lastID, req.resume)
+	defer h.unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for _, e := range backlog {
+		if req.match(e) {
+			if err := writeSSE(w, e); err != nil {
+				return
+			}
+		}
+	}
+	flusher.Flush()
+
+	ticker := time.NewTicker(keepAliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case e, ok := <-sub.events:
+			if !ok {
+				return
+			}
+			if !req.match(e) {
+				continue
+			}
+			if err := writeSSE(w, e); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+func writeSSE(w http.ResponseWriter, e *Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if e.ID != 0 {
+		if _, err := fmt.Fprintf(w, "id: %d\n", e.ID); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Kind, data)
+	return err
+}
+
+// ServeWebSocket streams the events as JSON text messages over a WebSocket
+func (h *Hub) ServeWebSocket(w http.ResponseWriter, r *http.Request) {
+	req, err := parseRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader already replied to the client
+		return
+	}
+	defer conn.Close()
+
+	sub, backlog := h.subscribe(req.lastID, req.resume)
+	defer h.unsubscribe(sub)
+
+	// Incoming messages are discarded, reading is required to process the control frames and detect disconnections
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for _, e := range backlog {
+		if req.match(e) {
+			if err := conn.WriteJSON(e); err != nil {
+				return
+			}
+		}
+	}
+
+	ticker := time.NewTicker(keepAliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case e, ok := <-sub.events:
+			if !ok {
+				conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "client too slow"))
+				return
+			}
+			if !req.match(e) {
+				continue
+			}
+			if err := conn.WriteJSON(e); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(keepAliveInterval)); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package stream
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/ecadlabs/tezos-bot/models"
+)
+
+// KindGap is sent instead of the missed events when a client resumes from an event no longer buffered
+const KindGap = "gap"
+
+const subscriberSize = 64
+
+// Event is a dispatched event as sent to the stream clients
+type Event struct {
+	ID            uint64          `json:"id,omitempty"`
+	Kind          string          `json:"kind"`
+	Level         int             `json:"level,omitempty"`
+	BlockHash     string          `json:"block_hash,omitempty"`
+	OperationHash string          `json:"operation_hash,omitempty"`
+	Timestamp     time.Time       `json:"timestamp"`
+	Payload       json.RawMessage `json:"payload"`
+}
+
+type gap struct {
+	LastEventID uint64 `json:"last_event_id"`
+	OldestID    uint64 `json:"oldest_id"`
+}
+
+type subscriber struct {
+	events chan *Event
+}
+
+// Hub fans the dispatched events out to the live stream clients and keeps a bounded buffer of the
+// most recent ones so that clients can resume after a disconnection
+type Hub struct {
+	mu          sync.Mutex
+	size        int
+	seq         uint64
+	buffer      []*Event
+	log         *eventLog
+	subscribers map[*subscriber]struct{}
+}
+
+// NewHub create a new Hub buffering up to size events, the buffer is persisted to the file at path, empty to only
+// keep it in memory
+func NewHub(size int, path string) (*Hub, error) {
+	h := &Hub{
+		size:        size,
+		subscribers: make(map[*subscriber]struct{}),
+	}
+
+	if path != "" {
+		var err error
+		if h.log, h.buffer, err = openEventLog(path, size); err != nil {
+			return nil, err
+		}
+		if len(h.buffer) != 0 {
+			h.seq = h.buffer[len(h.buffer)-1].ID
+		}
+	}
+
+	return h, nil
+}
+
+// Close closes the file the buffer is persisted to
+func (h *Hub) Close() error {
+	if h.log == nil {
+		return nil
+	}
+	return h.log.close()
+}
+
+// Send assigns the next id to an event, buffers it and forwards it to the connected clients
+func (h *Hub) Send(kind string, ref models.BlockRef, event interface{}) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+
+	h.seq++
+	e := &Event{
+		ID:            h.seq,
+		Kind:          kind,
+		Level:         ref.Level,
+		BlockHash:     ref.BlockHash,
+		OperationHash: ref.OperationHash,
+		Timestamp:     ref.Timestamp,
+		Payload:       payload,
+	}
+	if e.Timestamp.IsZero() {
+		e.Timestamp = time.Now().UTC()
+	}
+	h.buffer = append(h.buffer, e)
+	h.trim()
+
+	for sub := range h.subscribers {
+		select {
+		case sub.events <- e:
+		default:
+			// The client is too slow, it is disconnected and will resume from its last event
+			h.remove(sub)
+		}
+	}
+
+	if h.log == nil {
+		h.mu.Unlock()
+		return nil
+	}
+
+	// The log is locked before the hub is unlocked so that the events are written in order, it is rewritten with
+	// the buffer once it holds twice as many events
+	var compact []*Event
+	h.log.mu.Lock()
+	if h.log.lines+1 >= 2*h.size {
+		compact = append([]*Event(nil), h.buffer...)
+	}
+	h.mu.Unlock()
+	defer h.log.mu.Unlock()
+
+	if compact != nil {
+		return h.log.rewrite(compact)
+	}
+	return h.log.append(e)
+}
+
+// subscribe registers a new client, when resume is true the buffered events following lastID are returned
+// along with a gap event if some of them are no longer buffered
+func (h *Hub) subscribe(lastID uint64, resume bool) (*subscriber, []*Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var backlog []*Event
+	if resume {
+		if lastID > h.seq || (len(h.buffer) != 0 && lastID+1 < h.buffer[0].ID) {
+			backlog = append(backlog, h.gap(lastID))
+		}
+		for _, e := range h.buffer {
+			if e.ID > lastID || lastID > h.seq {
+				backlog = append(backlog, e)
+			}
+		}
+	}
+
+	sub := &subscriber{events: make(chan *Event, subscriberSize)}
+	h.subscribers[sub] = struct{}{}
+	return sub, backlog
+}
+
+func (h *Hub) unsubscribe(sub *subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.remove(sub)
+}
+
+func (h *Hub) remove(sub *subscriber) {
+	if _, ok := h.subscribers[sub]; ok {
+		delete(h.subscribers, sub)
+		close(sub.events)
+	}
+}
+
+func (h *Hub) gap(lastID uint64) *Event {
+	g := gap{LastEventID: lastID}
+	if len(h.buffer) != 0 {
+		g.OldestID = h.buffer[0].ID
+	}
+	payload, _ := json.Marshal(g)
+	return &Event{
+		Kind:      KindGap,
+		Timestamp: time.Now().UTC(),
+		Payload:   payload,
+	}
+}
+
+func (h *Hub) trim() {
+	if h.size > 0 && len(h.buffer) > h.size {
+		h.buffer = append([]*Event(nil), h.buffer[len(h.buffer)-h.size:]...)
+	}
+}
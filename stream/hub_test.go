@@ -0,0 +1,116 @@
+package stream
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ecadlabs/tezos-bot/models"
+)
+
+// ids lists the ids of the events, a gap event is listed as 0
+func ids(events []*Event) []uint64 {
+	res := []uint64{}
+	for _, e := range events {
+		if e.Kind == KindGap {
+			res = append(res, 0)
+		} else {
+			res = append(res, e.ID)
+		}
+	}
+	return res
+}
+
+func sendEvents(t *testing.T, h *Hub, n int) {
+	for i := 0; i < n; i++ {
+		if err := h.Send("block", models.BlockRef{Level: i + 1}, struct{}{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestHubResume(t *testing.T) {
+	// Events 1 to 8 are sent to a hub buffering 5 of them, 4 to 8 are left
+	h, err := NewHub(5, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sendEvents(t, h, 8)
+
+	tests := []struct {
+		name    string
+		lastID  uint64
+		resume  bool
+		want    []uint64
+		wantGap *gap
+	}{
+		{name: "new client", resume: false, want: []uint64{}},
+		{name: "up to date", lastID: 8, resume: true, want: []uint64{}},
+		{name: "buffered", lastID: 5, resume: true, want: []uint64{6, 7, 8}},
+		{name: "right before the buffer", lastID: 3, resume: true, want: []uint64{4, 5, 6, 7, 8}},
+		{name: "older than the buffer", lastID: 2, resume: true, want: []uint64{0, 4, 5, 6, 7, 8}, wantGap: &gap{LastEventID: 2, OldestID: 4}},
+		// A client which saw the events of a previous hub whose log was lost
+		{name: "ahead of the hub", lastID: 20, resume: true, want: []uint64{0, 4, 5, 6, 7, 8}, wantGap: &gap{LastEventID: 20, OldestID: 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub, backlog := h.subscribe(tt.lastID, tt.resume)
+			defer h.unsubscribe(sub)
+
+			if got := ids(backlog); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("subscribe(%d, %v) = %v, want %v", tt.lastID, tt.resume, got, tt.want)
+			}
+			if tt.wantGap != nil {
+				var g gap
+				if err := json.Unmarshal(backlog[0].Payload, &g); err != nil {
+					t.Fatal(err)
+				}
+				if g != *tt.wantGap {
+					t.Errorf("gap = %+v, want %+v", g, *tt.wantGap)
+				}
+			}
+		})
+	}
+}
+
+func TestHubPersistence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "stream.log")
+
+	// 12 events go through the compaction of a log holding twice as many events as the buffer
+	h, err := NewHub(5, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sendEvents(t, h, 12)
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if h, err = NewHub(5, path); err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	sub, backlog := h.subscribe(9, true)
+	h.unsubscribe(sub)
+	if got, want := ids(backlog), []uint64{10, 11, 12}; !reflect.DeepEqual(got, want) {
+		t.Errorf("subscribe(9) after a restart = %v, want %v", got, want)
+	}
+
+	// The ids carry on from the reloaded buffer
+	sendEvents(t, h, 1)
+	sub, backlog = h.subscribe(12, true)
+	h.unsubscribe(sub)
+	if got, want := ids(backlog), []uint64{13}; !reflect.DeepEqual(got, want) {
+		t.Errorf("subscribe(12) = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,126 @@
+package stream
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// eventLog persists the buffered events as an append only file, one JSON event per line
+type eventLog struct {
+	mu    sync.Mutex
+	path  string
+	file  *os.File
+	lines int
+}
+
+// openEventLog reads the events logged at path, the last size of them are returned, and rewrites the file with
+// them so that it does not grow across restarts and a line cut short by a crash is dropped
+func openEventLog(path string, size int) (*eventLog, []*Event, error) {
+	events, err := readEventLog(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(events) > size {
+		events = events[len(events)-size:]
+	}
+
+	l := &eventLog{path: path}
+	if err := l.rewrite(events); err != nil {
+		return nil, nil, err
+	}
+	return l, events, nil
+}
+
+func readEventLog(path string) ([]*Event, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var events []*Event
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			// The last line is only complete when it ends with a newline
+			return events, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		e := &Event{}
+		if err := json.Unmarshal(line, e); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+}
+
+// append writes an event at the end of the log
+func (l *eventLog) append(e *Event) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err := l.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	l.lines++
+	return nil
+}
+
+// rewrite replaces the log with events, the file is replaced atomically
+func (l *eventLog) rewrite(events []*Event) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, e := range events {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(l.path), filepath.Base(l.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), l.path); err != nil {
+		return err
+	}
+
+	if l.file != nil {
+		l.file.Close()
+	}
+	if l.file, err = os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND, 0600); err != nil {
+		return err
+	}
+	l.lines = len(events)
+	return nil
+}
+
+func (l *eventLog) close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
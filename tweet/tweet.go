@@ -0,0 +1,68 @@
+package tweet
+
+import "strings"
+
+const (
+	// MaxLength is the maximum length of a tweet
+	MaxLength = 280
+	// urlLength is the length twitter counts for a link, whatever its actual length
+	urlLength = 23
+)
+
+// lightRanges are the code points twitter counts as 1 character (latin, greek, cyrillic, hebrew, arabic,
+// devanagari... and general punctuation), every other one counts as 2
+var lightRanges = []struct{ from, to rune }{
+	{0x0000, 0x10FF},
+	{0x2000, 0x200D},
+	{0x2010, 0x201F},
+	{0x2032, 0x2037},
+}
+
+// Length returns the length of a status as counted by twitter: every link counts as 23 characters, CJK
+// characters and emojis count as 2 and most other characters as 1
+func Length(status string) int {
+	length := 0
+	for _, word := range strings.Fields(status) {
+		if strings.HasPrefix(word, "http://") || strings.HasPrefix(word, "https://") {
+			length += urlLength - weight(word)
+		}
+	}
+	return length + weight(status)
+}
+
+// weight returns the weighted length of text, an emoji counts as 2 along with its variation selector,
+// skin tone and the emojis joined to it, and a flag as 2 for its pair of regional indicators
+func weight(text string) int {
+	w := 0
+	emoji, joined, flag := false, false, false
+	for _, r := range text {
+		if emoji {
+			switch {
+			case r == 0x200D:
+				// Zero width joiner, the next emoji is part of the sequence
+				joined = true
+				continue
+			case joined:
+				joined = false
+				continue
+			case r == 0xFE0F || (r >= 0x1F3FB && r <= 0x1F3FF):
+				continue
+			case flag && r >= 0x1F1E6 && r <= 0x1F1FF:
+				flag = false
+				continue
+			}
+		}
+
+		emoji = r >= 0x1F000 && r <= 0x1FAFF || r >= 0x2600 && r <= 0x27BF
+		flag = r >= 0x1F1E6 && r <= 0x1F1FF
+		joined = false
+		w += 2
+		for _, light := range lightRanges {
+			if r >= light.from && r <= light.to {
+				w--
+				break
+			}
+		}
+	}
+	return w
+}
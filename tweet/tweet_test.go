@@ -0,0 +1,36 @@
+package tweet
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLength(t *testing.T) {
+	tests := []struct {
+		name   string
+		status string
+		want   int
+	}{
+		{name: "ascii", status: "Tezos vote", want: 10},
+		{name: "link", status: "Read https://tezos.gitlab.io/active/voting.html", want: 5 + 23},
+		{name: "latin accents", status: "élection", want: 8},
+		{name: "cyrillic", status: "голос", want: 5},
+		{name: "japanese", status: "投票", want: 4},
+		{name: "korean", status: "투표 yay", want: 8},
+		{name: "general punctuation", status: "“yay” – 50%", want: 11},
+		{name: "emoji", status: "🗳 yay", want: 6},
+		{name: "variation selector", status: "✔️", want: 2},
+		{name: "skin tone", status: "👍🏽", want: 2},
+		{name: "zero width joiner", status: "👨‍👩‍👧", want: 2},
+		{name: "flag", status: "🇫🇷🇯🇵", want: 4},
+		{name: "cjk at the limit", status: strings.Repeat("票", MaxLength/2), want: MaxLength},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Length(tt.status); got != tt.want {
+				t.Errorf("Length(%q) = %d, want %d", tt.status, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/ecadlabs/tezos-bot/health"
+	"github.com/ecadlabs/tezos-bot/listen"
+	"github.com/ecadlabs/tezos-bot/publish"
+	"github.com/ecadlabs/tezos-bot/state"
+)
+
+const verifyTimeout = 30 * time.Second
+
+func runVerifyConfig(args []string) int {
+	fs := newFlagSet("verify-config", "[flags]", "Check that the config file can be read, has no unknown keys and is consistent.")
+	configFile := configFlag(fs)
+	connect := fs.Bool("connect", false, "Also check that the rpc nodes are reachable and the twitter credentials are valid.")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	c := defaultConfig()
+	if err := c.LoadStrict(*configFile); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", *configFile, err.Error())
+		return 1
+	}
+
+	errs := c.Validate()
+	for _, err := range errs {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", *configFile, err.Error())
+	}
+	if len(errs) != 0 {
+		return 1
+	}
+
+	if _, err := newScheduler(c, state.NewMemoryStore()); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", *configFile, err.Error())
+		return 1
+	}
+
+	if *connect {
+		exporter, err := listen.NewExporter(c, health.NewStatus())
+		if err == nil {
+			ctx, cancel := context.WithTimeout(context.Background(), verifyTimeout)
+			var level int
+			level, err = exporter.Head(ctx)
+			cancel()
+			if err == nil {
+				log.Printf("RPC node reachable, head at level %d\n", level)
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: rpc node not reachable: %s\n", *configFile, err.Error())
+			return 1
+		}
+
+		if c.GetTwitterAccessToken() != "" {
+			if _, err := publish.NewTwitterPublisher(c); err != nil {
+				fmt.Fprintf(os.Stderr, "%s: twitter credentials rejected: %s\n", *configFile, err.Error())
+				return 1
+			}
+			log.Println("Twitter credentials verified")
+		}
+	}
+
+	fmt.Printf("%s: ok\n", *configFile)
+	return 0
+}